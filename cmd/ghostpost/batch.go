@@ -0,0 +1,151 @@
+// cmd/ghostpost/batch.go
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// outcome is what happened to a single file during a batch run.
+type outcome string
+
+const (
+	outcomeCreated outcome = "created"
+	outcomeUpdated outcome = "updated"
+	outcomeSkipped outcome = "skipped"
+	outcomePulled  outcome = "pulled" // Ghost's version was written to the file
+	outcomeFailed  outcome = "failed"
+)
+
+type result struct {
+	File    string
+	Outcome outcome
+	Err     error
+}
+
+// findPosts expands files, directories and glob patterns into a sorted,
+// de-duplicated list of Markdown files. Directories are walked recursively.
+func findPosts(args []string) ([]string, error) {
+	seen := map[string]bool{}
+	var out []string
+	add := func(p string) {
+		p = filepath.Clean(p)
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: no such file or directory", arg)
+		}
+		for _, m := range matches {
+			info, err := os.Stat(m)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				add(m)
+				continue
+			}
+			err = filepath.WalkDir(m, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() {
+					// skip .git, .github and friends
+					if p != m && strings.HasPrefix(d.Name(), ".") {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if isMarkdown(p) {
+					add(p)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func isMarkdown(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// runBatch feeds files to at most n concurrent workers and returns one
+// result per file, in input order.
+func runBatch(files []string, n int, fn func(string) (outcome, error)) []result {
+	if n < 1 {
+		n = 1
+	}
+	results := make([]result, len(files))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				o, err := fn(files[i])
+				if err != nil {
+					o = outcomeFailed
+				}
+				results[i] = result{File: files[i], Outcome: o, Err: err}
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// printSummary writes one line per file plus totals, and reports how many
+// files failed.
+func printSummary(results []result) int {
+	counts := map[outcome]int{}
+	for _, r := range results {
+		counts[r.Outcome]++
+		switch r.Outcome {
+		case outcomeFailed:
+			fmt.Printf("✗ %-8s %s: %v\n", r.Outcome, r.File, r.Err)
+		case outcomeSkipped:
+			fmt.Printf("↻ %-8s %s\n", r.Outcome, r.File)
+		case outcomePulled:
+			fmt.Printf("⇣ %-8s %s\n", r.Outcome, r.File)
+		default:
+			fmt.Printf("✓ %-8s %s\n", r.Outcome, r.File)
+		}
+	}
+	pulled := ""
+	if n := counts[outcomePulled]; n > 0 {
+		pulled = fmt.Sprintf(", %d pulled", n)
+	}
+	fmt.Printf("\n%d file(s): %d created, %d updated, %d skipped%s, %d failed\n",
+		len(results), counts[outcomeCreated], counts[outcomeUpdated],
+		counts[outcomeSkipped], pulled, counts[outcomeFailed])
+	return counts[outcomeFailed]
+}
@@ -0,0 +1,36 @@
+// cmd/ghostpost/css.go
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/render"
+	"github.com/spf13/cobra"
+)
+
+func cssCmd() *cobra.Command {
+	var style string
+
+	cmd := &cobra.Command{
+		Use:   "css",
+		Short: "Print the stylesheet for highlighted code",
+		Long: `Print the CSS that colours code highlighted with classes
+(highlight.classes), in a <style> tag. Paste it into Ghost's site header
+code injection (Settings → Code injection).`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if style == "" {
+				style = cfg.Highlight.Style
+			}
+			css, err := render.CSS(style)
+			if err != nil {
+				return err
+			}
+			fmt.Print(css)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&style, "style", "", "Chroma style (default highlight.style, or github)")
+	return cmd
+}
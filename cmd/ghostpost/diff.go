@@ -0,0 +1,241 @@
+// cmd/ghostpost/diff.go
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/api"
+)
+
+// change is one field that differs between Ghost and the local file.
+type change struct {
+	Field string   `json:"field"`
+	Old   string   `json:"old,omitempty"`
+	New   string   `json:"new,omitempty"`
+	Lines []string `json:"lines,omitempty"` // body only: "- old" / "+ new"
+}
+
+// diffPost compares the payload publish would send with the live post.
+// On create, empty local values are not sent to Ghost, so they never show as
+// changes. On update they clear the field (see api.UpdateFields), except for
+// the ghostOwned fields, which are never sent.
+func diffPost(remote, local api.Post, update bool, ghostOwned []string) []change {
+	skip := map[string]bool{}
+	if update {
+		for _, k := range ghostOwned {
+			skip[k] = true
+		}
+		if local.Visibility == "" {
+			local.Visibility = "public"
+		}
+	}
+
+	var out []change
+	str := func(field, old, new string) {
+		if !skip[field] && old != new && (new != "" || update) {
+			out = append(out, change{Field: field, Old: old, New: new})
+		}
+	}
+	list := func(field string, old, new []string) {
+		if !skip[field] && !api.EqualStringSlices(old, new) && (len(new) > 0 || update) {
+			out = append(out, change{Field: field, Old: strings.Join(old, ", "), New: strings.Join(new, ", ")})
+		}
+	}
+
+	str("title", remote.Title, local.Title)
+	if local.Slug != "" {
+		str("slug", remote.Slug, local.Slug)
+	}
+	str("status", remote.Status, local.Status)
+	if local.PublishedAt != "" {
+		str("published_at", remote.PublishedAt, local.PublishedAt)
+	}
+	str("visibility", remote.Visibility, local.Visibility)
+	str("custom_excerpt", remote.CustomExcerpt, local.CustomExcerpt)
+	str("custom_template", remote.CustomTemplate, local.CustomTemplate)
+	str("feature_image", remote.FeatureImage, local.FeatureImage)
+	str("og_image", remote.OGImage, local.OGImage)
+	str("twitter_image", remote.TwitterImage, local.TwitterImage)
+	if local.Featured != remote.Featured && !skip["featured"] {
+		out = append(out, change{Field: "featured", Old: fmt.Sprint(remote.Featured), New: fmt.Sprint(local.Featured)})
+	}
+	list("tags", tagNames(remote.Tags), tagNames(local.Tags))
+	if len(local.Tiers) > 0 {
+		list("tiers", tierNames(remote.Tiers), tierNames(local.Tiers))
+	}
+	if len(local.Authors) > 0 {
+		list("authors", authorIDs(remote.Authors), authorIDs(local.Authors))
+	}
+
+	if skip["html"] {
+		return out
+	}
+	if lines := lineDiff(htmlLines(remote.HTML), htmlLines(local.HTML)); len(lines) > 0 {
+		out = append(out, change{Field: "html", Lines: lines})
+	}
+	return out
+}
+
+func tagNames(tags []api.TagRef) []string {
+	var out []string
+	for _, t := range tags {
+		out = append(out, t.Name)
+	}
+	return out
+}
+
+func tierNames(tiers []api.TierRef) []string {
+	var out []string
+	for _, t := range tiers {
+		out = append(out, t.Name)
+	}
+	return out
+}
+
+func authorIDs(authors []api.AuthorRef) []string {
+	var out []string
+	for _, a := range authors {
+		out = append(out, a.ID)
+	}
+	return out
+}
+
+var blockEndRe = regexp.MustCompile(`(</(?:p|h[1-6]|ul|ol|li|blockquote|pre|figure|div|table|tr)>|<hr\s*/?>|<br\s*/?>)\s*`)
+
+// htmlLines splits HTML at block boundaries so that Ghost's reformatting
+// of whitespace between blocks doesn't show up as a change.
+func htmlLines(html string) []string {
+	html = blockEndRe.ReplaceAllString(html, "$1\n")
+	var out []string
+	for _, l := range strings.Split(html, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// lineDiff returns the removed ("- ") and added ("+ ") lines needed to turn
+// a into b: a shortest edit script, found with Myers' algorithm in linear
+// space, so long posts don't need a len(a)×len(b) table.
+func lineDiff(a, b []string) []string {
+	var out []string
+	diffLines(a, b, &out)
+	return out
+}
+
+func diffLines(a, b []string, out *[]string) {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	if len(a) == 0 || len(b) == 0 {
+		for _, l := range a {
+			*out = append(*out, "- "+l)
+		}
+		for _, l := range b {
+			*out = append(*out, "+ "+l)
+		}
+		return
+	}
+	x, y, ok := middleSnake(a, b)
+	if !ok {
+		diffLines(a, nil, out)
+		diffLines(nil, b, out)
+		return
+	}
+	diffLines(a[:x], b[:y], out)
+	diffLines(a[x:], b[y:], out)
+}
+
+// middleSnake runs Myers' search from both ends of a and b at once, and
+// returns where the two paths meet: a point on a shortest edit script that
+// splits it in two. ok is false if a and b have nothing in common.
+func middleSnake(a, b []string) (x, y int, ok bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	off := maxD
+	fwd := make([]int, 2*maxD+2) // furthest x on each diagonal k = x-y, at off+k
+	bwd := make([]int, 2*maxD+2) // the same, counted from the ends
+	for i := range fwd {
+		fwd[i], bwd[i] = -1, -1
+	}
+	fwd[off+1], bwd[off+1] = 0, 0
+	delta := n - m
+	front := delta%2 != 0 // the forward path meets the backward one
+	// Diagonals that ran off the edges of the grid are skipped.
+	kfStart, kfEnd, kbStart, kbEnd := 0, 0, 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + kfStart; k <= d-kfEnd; k += 2 {
+			i := off + k
+			var x1 int
+			if k == -d || (k != d && fwd[i-1] < fwd[i+1]) {
+				x1 = fwd[i+1]
+			} else {
+				x1 = fwd[i-1] + 1
+			}
+			y1 := x1 - k
+			for x1 < n && y1 < m && a[x1] == b[y1] {
+				x1++
+				y1++
+			}
+			fwd[i] = x1
+			switch {
+			case x1 > n:
+				kfEnd += 2
+			case y1 > m:
+				kfStart += 2
+			case front:
+				if j := off + delta - k; j >= 0 && j < len(bwd) && bwd[j] != -1 && x1 >= n-bwd[j] {
+					return x1, y1, true
+				}
+			}
+		}
+		for k := -d + kbStart; k <= d-kbEnd; k += 2 {
+			i := off + k
+			var x2 int
+			if k == -d || (k != d && bwd[i-1] < bwd[i+1]) {
+				x2 = bwd[i+1]
+			} else {
+				x2 = bwd[i-1] + 1
+			}
+			y2 := x2 - k
+			for x2 < n && y2 < m && a[n-x2-1] == b[m-y2-1] {
+				x2++
+				y2++
+			}
+			bwd[i] = x2
+			switch {
+			case x2 > n:
+				kbEnd += 2
+			case y2 > m:
+				kbStart += 2
+			case !front:
+				if j := off + delta - k; j >= 0 && j < len(fwd) && fwd[j] != -1 && fwd[j] >= n-x2 {
+					x1 := fwd[j]
+					return x1, x1 - (j - off), true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func printChanges(changes []change) {
+	for _, c := range changes {
+		if c.Field == "html" {
+			fmt.Printf("    ~ html:\n")
+			for _, l := range c.Lines {
+				fmt.Printf("        %s\n", l)
+			}
+			continue
+		}
+		fmt.Printf("    ~ %s: %q → %q\n", c.Field, c.Old, c.New)
+	}
+}
@@ -0,0 +1,97 @@
+// cmd/ghostpost/diff_test.go
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string // lines, separated by spaces
+		want []string
+	}{
+		{"same", "a b c", "a b c", nil},
+		{"both empty", "", "", nil},
+		{"all new", "", "a b", []string{"+ a", "+ b"}},
+		{"all gone", "a b", "", []string{"- a", "- b"}},
+		{"changed line", "a b c", "a x c", []string{"- b", "+ x"}},
+		{"added at end", "a b", "a b c", []string{"+ c"}},
+		{"removed at start", "a b c", "b c", []string{"- a"}},
+		{"moved", "a b c d", "b c d a", []string{"- a", "+ a"}},
+		{"nothing in common", "a b", "x y", []string{"- a", "- b", "+ x", "+ y"}},
+		{"repeated lines", "a b a b a", "b a b", []string{"- a", "- a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lineDiff(strings.Fields(tt.a), strings.Fields(tt.b)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lineDiff(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLineDiffIsShortest checks lineDiff against a longest common
+// subsequence on random inputs: the removed lines are a's lines outside it
+// and the added ones b's.
+func TestLineDiffIsShortest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	lines := func() []string {
+		out := make([]string, r.Intn(40))
+		for i := range out {
+			out[i] = fmt.Sprint(r.Intn(5))
+		}
+		return out
+	}
+	for i := 0; i < 500; i++ {
+		a, b := lines(), lines()
+		var removed, added []string
+		for _, l := range lineDiff(a, b) {
+			if s, ok := strings.CutPrefix(l, "- "); ok {
+				removed = append(removed, s)
+			} else {
+				added = append(added, strings.TrimPrefix(l, "+ "))
+			}
+		}
+		n := lcsLen(a, b)
+		if len(a)-len(removed) != n || len(b)-len(added) != n {
+			t.Fatalf("lineDiff(%q, %q): %d removed, %d added, want %d and %d",
+				a, b, len(removed), len(added), len(a)-n, len(b)-n)
+		}
+		if !isSubsequence(removed, a) || !isSubsequence(added, b) {
+			t.Fatalf("lineDiff(%q, %q) = %q, %q: not lines of the inputs", a, b, removed, added)
+		}
+	}
+}
+
+func lcsLen(a, b []string) int {
+	row := make([]int, len(b)+1)
+	for i := range a {
+		prev := 0
+		for j := range b {
+			cur := row[j+1]
+			if a[i] == b[j] {
+				row[j+1] = prev + 1
+			} else {
+				row[j+1] = max(row[j+1], row[j])
+			}
+			prev = cur
+		}
+	}
+	return row[len(b)]
+}
+
+func isSubsequence(sub, of []string) bool {
+	i := 0
+	for _, l := range of {
+		if i < len(sub) && sub[i] == l {
+			i++
+		}
+	}
+	return i == len(sub)
+}
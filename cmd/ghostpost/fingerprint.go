@@ -0,0 +1,70 @@
+// cmd/ghostpost/fingerprint.go
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/frontmatter"
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/images"
+)
+
+// asset is a local file a post references, identified by its content.
+type asset struct {
+	Ref string // as written in the post
+	Sum string // SHA256 of the file, or "missing"
+}
+
+// localAssets digests every local file md and meta refer to, in a stable
+// order.
+func localAssets(meta frontmatter.Meta, md []byte, dir string) []asset {
+	refs := images.Refs(md, dir, uploadLimits())
+	for _, f := range meta.ImageFields() {
+		if path := images.Resolve(*f.Value, dir); path != "" {
+			refs = append(refs, images.Ref{Dest: *f.Value, Path: path})
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Dest < refs[j].Dest })
+
+	var out []asset
+	for i, ref := range refs {
+		if ref.Path == "" || i > 0 && refs[i-1].Dest == ref.Dest {
+			continue
+		}
+		a := asset{Ref: ref.Dest, Sum: "missing"}
+		if raw, err := os.ReadFile(ref.Path); err == nil {
+			h := sha256.Sum256(raw)
+			a.Sum = hex.EncodeToString(h[:])
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// fingerprint identifies everything about a post that publishing depends
+// on: the front-matter (minus the keys ghostpost manages itself), the
+// rendered body and the bytes of every local asset. Reformatting that
+// changes none of those doesn't change the fingerprint.
+func fingerprint(meta frontmatter.Meta, body string, assets []asset) string {
+	meta.PostID, meta.Hash = "", ""
+	meta.RemoteUpdatedAt, meta.RemoteHash = "", ""
+	// an empty list and a missing key mean the same thing
+	for _, l := range []*[]string{&meta.Tags, &meta.Authors, &meta.Tiers} {
+		if len(*l) == 0 {
+			*l = nil
+		}
+	}
+
+	h := sha256.New()
+	_ = json.NewEncoder(h).Encode(meta)
+	fmt.Fprintf(h, "%d\n%s", len(body), body)
+	for _, a := range assets {
+		fmt.Fprintf(h, "%s\x00%s\n", a.Ref, a.Sum)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
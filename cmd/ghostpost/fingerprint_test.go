@@ -0,0 +1,51 @@
+// cmd/ghostpost/fingerprint_test.go
+
+package main
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	const base = "---\ntitle: A\ntags: [go]\nfeature_image: hero.png\n---\nHello ![pic](pic.png)\n"
+	tests := []struct {
+		name    string
+		post    string
+		files   map[string]string // written next to the post
+		changed bool
+	}{
+		{"same", base, nil, false},
+		{"body", "---\ntitle: A\ntags: [go]\nfeature_image: hero.png\n---\nHello again ![pic](pic.png)\n", nil, true},
+		{"title", "---\ntitle: B\ntags: [go]\nfeature_image: hero.png\n---\nHello ![pic](pic.png)\n", nil, true},
+		{"tags", "---\ntitle: A\ntags: [go, web]\nfeature_image: hero.png\n---\nHello ![pic](pic.png)\n", nil, true},
+		{"excerpt added", "---\ntitle: A\ntags: [go]\nfeature_image: hero.png\ncustom_excerpt: Hi\n---\nHello ![pic](pic.png)\n", nil, true},
+		{"image in the body", base, map[string]string{"pic.png": "new pixels"}, true},
+		{"feature image", base, map[string]string{"hero.png": "new hero"}, true},
+		{"post_id and hash", "---\ntitle: A\ntags: [go]\nfeature_image: hero.png\npost_id: p1\nhash: abc\n---\nHello ![pic](pic.png)\n", nil, false},
+		{"remote state", "---\ntitle: A\ntags: [go]\nfeature_image: hero.png\nremote_updated_at: \"2026-01-01T00:00:00Z\"\nremote_hash: def\n---\nHello ![pic](pic.png)\n", nil, false},
+		{"key order and comments", "---\n# the post\nfeature_image: hero.png\ntags:\n  - go\ntitle: A\n---\nHello ![pic](pic.png)\n", nil, false},
+		{"trailing blank lines", "---\ntitle: A\ntags: [go]\nfeature_image: hero.png\n---\nHello ![pic](pic.png)\n\n\n", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestPublisher(t)
+			writePost(t, "posts/pic.png", "pixels")
+			writePost(t, "posts/hero.png", "hero")
+			writePost(t, "posts/a.md", base)
+			before, err := loadSource(nil, "posts/a.md")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			writePost(t, "posts/a.md", tt.post)
+			for name, content := range tt.files {
+				writePost(t, "posts/"+name, content)
+			}
+			after, err := loadSource(nil, "posts/a.md")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed := after.Hash != before.Hash; changed != tt.changed {
+				t.Errorf("hash changed = %v, want %v", changed, tt.changed)
+			}
+		})
+	}
+}
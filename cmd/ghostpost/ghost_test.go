@@ -0,0 +1,201 @@
+// cmd/ghostpost/ghost_test.go
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/api"
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/config"
+)
+
+// fakeGhost is a stand-in for the Admin API: posts and pages kept in
+// memory, with Ghost's updated_at collision check on update.
+type fakeGhost struct {
+	*httptest.Server
+	mu    sync.Mutex
+	posts map[string]api.Post // by ID, pages included
+	puts  []map[string]any    // the fields of every update, in order
+	clock int
+}
+
+func newFakeGhost(t *testing.T) *fakeGhost {
+	t.Helper()
+	g := &fakeGhost{posts: map[string]api.Post{}}
+	g.Server = httptest.NewServer(http.HandlerFunc(g.serve))
+	t.Cleanup(g.Close)
+	return g
+}
+
+func (g *fakeGhost) tick() string {
+	g.clock++
+	return fmt.Sprintf("2026-01-01T00:00:%02d.000Z", g.clock)
+}
+
+// Get returns the post with id as Ghost has it.
+func (g *fakeGhost) Get(id string) api.Post {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.posts[id]
+}
+
+// Edit changes a post as an edit in Ghost's editor would.
+func (g *fakeGhost) Edit(id string, edit func(*api.Post)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	p := g.posts[id]
+	edit(&p)
+	p.UpdatedAt = g.tick()
+	g.posts[id] = p
+}
+
+func (g *fakeGhost) serve(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/ghost/api/admin/"), "/")
+	parts := strings.Split(path, "/")
+	reply := func(status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+	fail := func(status int, msg string) {
+		reply(status, map[string]any{"errors": []map[string]string{{"message": msg}}})
+	}
+
+	switch kind := parts[0]; {
+	case kind == "authors" || kind == "tiers":
+		reply(200, map[string][]any{kind: {}})
+	case kind == "images" || kind == "media" || kind == "files":
+		_, h, err := r.FormFile("file")
+		if err != nil {
+			fail(400, err.Error())
+			return
+		}
+		reply(201, map[string][]map[string]string{kind: {{"url": g.URL + "/content/" + kind + "/" + h.Filename}}})
+	case (kind == "posts" || kind == "pages") && r.Method == http.MethodPost:
+		var body map[string][]api.Post
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body[kind]) != 1 {
+			fail(400, "bad request")
+			return
+		}
+		p := body[kind][0]
+		p.ID = fmt.Sprintf("id%d", len(g.posts)+1)
+		p.UpdatedAt = g.tick()
+		p.URL = g.URL + "/" + p.ID + "/"
+		if p.Status == "" {
+			p.Status = "draft"
+		}
+		g.posts[p.ID] = p
+		reply(201, map[string][]api.Post{kind: {p}})
+	case (kind == "posts" || kind == "pages") && len(parts) == 2:
+		p, ok := g.posts[parts[1]]
+		if !ok {
+			fail(404, "Resource not found")
+			return
+		}
+		if r.Method == http.MethodPut {
+			var body map[string][]map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body[kind]) != 1 {
+				fail(400, "bad request")
+				return
+			}
+			fields := body[kind][0]
+			if fields["updated_at"] != p.UpdatedAt {
+				fail(409, "Saving failed! Someone else is editing this post.")
+				return
+			}
+			g.puts = append(g.puts, fields)
+			p = update(p, fields)
+			p.UpdatedAt = g.tick()
+			g.posts[p.ID] = p
+		}
+		reply(200, map[string][]api.Post{kind: {p}})
+	default:
+		fail(404, "Resource not found")
+	}
+}
+
+// update applies the fields of an update request to p, as Ghost does:
+// null clears a field.
+func update(p api.Post, fields map[string]any) api.Post {
+	raw, _ := json.Marshal(p)
+	var m map[string]any
+	json.Unmarshal(raw, &m)
+	for k, v := range fields {
+		if v == nil {
+			delete(m, k)
+		} else {
+			m[k] = v
+		}
+	}
+	raw, _ = json.Marshal(m)
+	var out api.Post
+	json.Unmarshal(raw, &out)
+	return out
+}
+
+// newTestPublisher runs in a new repository, with the config pointing at a
+// fresh fakeGhost.
+func newTestPublisher(t *testing.T) (*publisher, *fakeGhost) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir(".git", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	g := newFakeGhost(t)
+	cfg = &config.Config{APIURL: g.URL + "/ghost/api/admin/", AdminJWT: "jwt"}
+	sidecar = nil
+	linkURLs, warned, mdFiles = map[string]linkResult{}, map[string]bool{}, nil
+	p, err := newPublisher(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, g
+}
+
+// writePost writes a Markdown file, making its directory.
+func writePost(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// published writes a post and publishes it, and returns its ID.
+func published(t *testing.T, p *publisher, file, content string) string {
+	t.Helper()
+	writePost(t, file, content)
+	if _, err := p.publish(file); err != nil {
+		t.Fatalf("publish %s: %v", file, err)
+	}
+	meta, _, err := parseFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return meta.PostID
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewRGBA(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
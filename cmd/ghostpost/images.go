@@ -2,14 +2,320 @@
 
 package main
 
-import "github.com/spf13/cobra"
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/images"
+
+	"github.com/spf13/cobra"
+)
 
 func imagesCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "images",
-		Short: "Image operations (placeholder)",
-		Run: func(_ *cobra.Command, _ []string) {
-			println("Not implemented yet—coming soon.")
+		Short: "Upload, list and verify images",
+		Long: `Every upload is recorded in the image cache (image_cache, by default
+.ghostpost/images.json), keyed by the file's content, so the same image is
+never uploaded twice. Audio, video and other files linked from posts are
+uploaded and cached the same way.`,
+	}
+
+	cmd.AddCommand(imagesUploadCmd())
+	cmd.AddCommand(imagesLsCmd())
+	cmd.AddCommand(imagesVerifyCmd())
+	return cmd
+}
+
+// uploadLimits are the uploads section of the config.
+func uploadLimits() images.Limits {
+	return images.Limits{
+		MaxImage:   cfg.Uploads.MaxImageSize,
+		MaxMedia:   cfg.Uploads.MaxMediaSize,
+		MaxFile:    cfg.Uploads.MaxFileSize,
+		ImageTypes: cfg.Uploads.ImageTypes,
+		MediaTypes: cfg.Uploads.MediaTypes,
+		FileTypes:  cfg.Uploads.FileTypes,
+	}
+}
+
+// newImages returns the image service for this run, backed by the image
+// cache unless it is disabled. Every post in a batch shares it.
+func newImages() (*images.Service, error) {
+	s := images.New(cfg.APIURL, cfg.AdminJWT, httpClient)
+	s.Optimize = images.Options{
+		MaxWidth:      cfg.Images.MaxWidth,
+		Quality:       cfg.Images.Quality,
+		WebP:          cfg.Images.WebP,
+		StripMetadata: cfg.Images.StripMetadata,
+	}
+	s.Limits = uploadLimits()
+	s.Mirror = images.Mirror{
+		Enabled: cfg.Images.Mirror,
+		Allow:   cfg.Images.MirrorAllow,
+		Deny:    cfg.Images.MirrorDeny,
+	}
+	if cfg.ImageCache == "" {
+		return s, nil
+	}
+	m, err := loadImageCache()
+	if err != nil {
+		return nil, err
+	}
+	s.Manifest = m
+	return s, nil
+}
+
+func loadImageCache() (*images.Manifest, error) {
+	if cfg.ImageCache == "" {
+		return nil, fmt.Errorf("no image cache configured: set image_cache or pass --image-cache")
+	}
+	m, err := images.LoadManifest(cfg.ImageCache, cfg.APIURL)
+	if err != nil {
+		return nil, err
+	}
+	if m.Dropped > 0 {
+		fmt.Printf("warning: ignoring %d unusable entries in %s; those images will be uploaded again\n", m.Dropped, cfg.ImageCache)
+	}
+	return m, nil
+}
+
+// findImages expands files and directories into image files. Named files
+// are taken as they are; directories are walked for known image, audio
+// and video types.
+func findImages(args []string) ([]string, error) {
+	var out []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			out = append(out, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if p != arg && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if images.KindOf(p) != images.KindFile {
+				out = append(out, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func imagesUploadCmd() *cobra.Command {
+	var concurrency int
+
+	cmd := &cobra.Command{
+		Use:   "upload <dir|file>...",
+		Short: "Upload images ahead of publishing",
+		Long: `Upload images to Ghost and record them in the image cache. Images
+already there are skipped, so publishing later only swaps in the URLs.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := findImages(args)
+			if err != nil {
+				return err
+			}
+			s, err := newImages()
+			if err != nil {
+				return err
+			}
+
+			urls := make([]string, len(files))
+			index := make(map[string]int, len(files))
+			for i, f := range files {
+				index[f] = i
+			}
+			results := runBatch(files, concurrency, func(file string) (outcome, error) {
+				if url, ok, err := s.Cached(file); err != nil || ok {
+					urls[index[file]] = url
+					return outcomeSkipped, err
+				}
+				url, err := s.Upload(file)
+				urls[index[file]] = url
+				return outcomeCreated, err
+			})
+
+			uploaded, skipped, failed := 0, 0, 0
+			for i, r := range results {
+				switch r.Outcome {
+				case outcomeFailed:
+					fmt.Printf("✗ failed   %s: %v\n", r.File, r.Err)
+					failed++
+				case outcomeSkipped:
+					fmt.Printf("↻ exists   %s → %s\n", r.File, urls[i])
+					skipped++
+				default:
+					fmt.Printf("✓ uploaded %s → %s\n", r.File, urls[i])
+					uploaded++
+				}
+			}
+			fmt.Printf("\n%d image(s): %d uploaded, %d already uploaded, %d failed\n",
+				len(results), uploaded, skipped, failed)
+			if failed > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d of %d image(s) failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&concurrency, "concurrency", "j", 4, "Number of images to upload in parallel")
+	return cmd
+}
+
+func imagesLsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ls [file|dir|glob]...",
+		Short: "List local images used by posts and their Ghost URLs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+			posts, err := findPosts(args)
+			if err != nil {
+				return err
+			}
+			s, err := newImages()
+			if err != nil {
+				return err
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "POST\tIMAGE\tURL")
+			missing, pending := 0, 0
+			for _, post := range posts {
+				meta, md, err := parseFile(post)
+				if err != nil {
+					return fmt.Errorf("%s: %w", post, err)
+				}
+				dir := filepath.Dir(post)
+				refs := images.Refs(md, dir, s.Limits)
+				for _, f := range meta.ImageFields() {
+					refs = append(refs, images.Ref{Dest: *f.Value})
+				}
+				for _, ref := range refs {
+					src := s.Source(ref.Dest, dir)
+					if src == "" {
+						continue // remote, and not mirrored
+					}
+					url, ok, err := s.Cached(src)
+					switch {
+					case err != nil:
+						url = "(missing file)"
+						missing++
+					case !ok:
+						url = "(not uploaded)"
+						pending++
+					}
+					fmt.Fprintf(tw, "%s\t%s\t%s\n", post, ref.Dest, url)
+				}
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			if missing+pending > 0 {
+				fmt.Printf("\n%d image(s) not uploaded yet, %d missing\n", pending, missing)
+			}
+			return nil
 		},
 	}
 }
+
+func imagesVerifyCmd() *cobra.Command {
+	var prune bool
+	var concurrency int
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check that every uploaded image still resolves",
+		Long: `Request every URL in the image cache and report the ones that no
+longer resolve. With --prune, broken entries are dropped from the cache so
+the images are uploaded again on the next publish.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			m, err := loadImageCache()
+			if err != nil {
+				return err
+			}
+			sums := m.Sums()
+			results := runBatch(sums, concurrency, func(sum string) (outcome, error) {
+				r, _ := m.Get(sum)
+				return outcomeSkipped, checkURL(r.URL)
+			})
+
+			var broken []string
+			for i, res := range results {
+				r, _ := m.Get(sums[i])
+				if res.Err != nil {
+					fmt.Printf("✗ %s → %s: %v\n", r.File, r.URL, res.Err)
+					broken = append(broken, sums[i])
+				} else {
+					fmt.Printf("✓ %s → %s\n", r.File, r.URL)
+				}
+			}
+			fmt.Printf("\n%d image(s): %d ok, %d broken\n", len(sums), len(sums)-len(broken), len(broken))
+			if len(broken) == 0 {
+				return nil
+			}
+			if prune {
+				if err := m.Delete(broken...); err != nil {
+					return err
+				}
+				fmt.Printf("Removed %d broken image(s) from %s\n", len(broken), cfg.ImageCache)
+				return nil
+			}
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d image(s) no longer resolve (use --prune to forget them)", len(broken))
+		},
+	}
+
+	cmd.Flags().BoolVar(&prune, "prune", false, "Drop broken images from the image cache")
+	cmd.Flags().IntVarP(&concurrency, "concurrency", "j", 8, "Number of URLs to check in parallel")
+	return cmd
+}
+
+// checkURL asks for url's headers, falling back to GET for servers that
+// don't do HEAD.
+func checkURL(url string) error {
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(context.Background(), method, url, nil)
+		if err != nil {
+			return err
+		}
+		res, err := httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		if res.StatusCode == http.StatusMethodNotAllowed && method == http.MethodHead {
+			continue
+		}
+		if res.StatusCode >= 400 {
+			return fmt.Errorf("%s", res.Status)
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+// cmd/ghostpost/links.go
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/api"
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/images"
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/render"
+)
+
+// siteRoot is the site's URL, from the Admin API URL.
+func siteRoot() string {
+	return strings.Split(cfg.APIURL, "/ghost/")[0]
+}
+
+// postLinks resolves the links in the post in file to other posts: a path
+// to a .md file, relative to the post (or to the repository root with a
+// leading /), or a [[wiki link]] naming a file anywhere in the repository.
+// Only files with a post_id are posts; links to other files are left
+// alone. A link to a missing file or a draft, or a wiki link to a file that
+// isn't published, is a warning, or with broken_links: fail, an error.
+// URLs are looked up in Ghost with client.
+func postLinks(client *api.Client, file string) render.Links {
+	dir := filepath.Dir(file)
+	return func(target string, wiki bool) (string, error) {
+		var path string
+		var err error
+		switch {
+		case wiki:
+			path, err = wikiTarget(dir, target)
+		case strings.HasPrefix(target, "/"):
+			path = filepath.Join(images.RepoRoot(dir), filepath.FromSlash(target))
+		default:
+			path = filepath.Join(dir, filepath.FromSlash(target))
+		}
+		url := ""
+		if err == nil {
+			url, err = postURL(client, path)
+		}
+		if err == nil && url == "" && wiki {
+			err = fmt.Errorf("[[%s]]: not published yet", target)
+		}
+		if err == nil {
+			return url, nil
+		}
+		if cfg.BrokenLinks == "fail" {
+			return "", err
+		}
+		warnOnce(fmt.Sprintf("warning: %s: %s", file, err))
+		return "", nil
+	}
+}
+
+var (
+	linkMu   sync.Mutex
+	linkURLs = map[string]linkResult{} // by cleaned path
+	warned   = map[string]bool{}
+	mdFiles  map[string][]string // by repository root
+)
+
+type linkResult struct {
+	url string
+	err error
+}
+
+// warnOnce prints msg unless it was printed already; posts are rendered
+// more than once per run.
+func warnOnce(msg string) {
+	linkMu.Lock()
+	defer linkMu.Unlock()
+	if !warned[msg] {
+		warned[msg] = true
+		fmt.Println(msg)
+	}
+}
+
+// postURL is the URL Ghost reports for the post in path, or "" if the
+// file has no post_id. A draft is an error.
+func postURL(client *api.Client, path string) (string, error) {
+	path = filepath.Clean(path)
+	linkMu.Lock()
+	r, ok := linkURLs[path]
+	linkMu.Unlock()
+	if ok {
+		return r.url, r.err
+	}
+
+	r.url, r.err = lookupPostURL(client, path)
+	linkMu.Lock()
+	linkURLs[path] = r
+	linkMu.Unlock()
+	return r.url, r.err
+}
+
+func lookupPostURL(client *api.Client, path string) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("link to %s: no such post", filepath.ToSlash(path))
+	}
+	meta, _, err := parseFile(path)
+	if err != nil {
+		return "", fmt.Errorf("link to %s: %w", filepath.ToSlash(path), err)
+	}
+	if meta.PostID == "" {
+		return "", nil // not a post, or not published yet
+	}
+	kind, err := kindOf(path, meta)
+	if err != nil {
+		return "", err
+	}
+	post, err := client.GetPost(context.Background(), kind, meta.PostID)
+	if err != nil {
+		return "", fmt.Errorf("link to %s: %w", filepath.ToSlash(path), err)
+	}
+	switch post.Status {
+	case "draft", "scheduled":
+		return "", fmt.Errorf("link to %s: not published yet (%s)", filepath.ToSlash(path), post.Status)
+	}
+	return post.URL, nil
+}
+
+// wikiTarget finds the Markdown file a wiki link names, by file name
+// without the extension, anywhere in the repository. A file next to the
+// linking post wins over one elsewhere.
+func wikiTarget(dir, name string) (string, error) {
+	if p := filepath.Join(dir, name+".md"); isFile(p) {
+		return p, nil
+	}
+	root := images.RepoRoot(dir)
+	linkMu.Lock()
+	files, ok := mdFiles[root]
+	if !ok {
+		files = markdownFiles(root)
+		if mdFiles == nil {
+			mdFiles = map[string][]string{}
+		}
+		mdFiles[root] = files
+	}
+	linkMu.Unlock()
+
+	var found []string
+	for _, f := range files {
+		base := filepath.Base(f)
+		if strings.EqualFold(strings.TrimSuffix(base, filepath.Ext(base)), name) {
+			found = append(found, f)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("[[%s]]: no such post", name)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("[[%s]] could be any of %s", name, strings.Join(found, ", "))
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// markdownFiles lists the Markdown files under root, skipping hidden
+// directories as findPosts does.
+func markdownFiles(root string) []string {
+	wd, _ := os.Getwd()
+	var out []string
+	_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isMarkdown(p) {
+			if rel, err := filepath.Rel(wd, p); err == nil && !strings.HasPrefix(rel, "..") {
+				p = rel
+			}
+			out = append(out, p)
+		}
+		return nil
+	})
+	return out
+}
@@ -0,0 +1,373 @@
+// cmd/ghostpost/plan.go
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/api"
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/images"
+
+	"github.com/spf13/cobra"
+)
+
+const planVersion = 1
+
+// plan is the reviewed, saved output of `ghostpost plan` that `ghostpost
+// apply` executes.
+type plan struct {
+	Version int         `json:"version"`
+	APIURL  string      `json:"api_url"`
+	Created string      `json:"created"`
+	Entries []planEntry `json:"entries"`
+}
+
+type planEntry struct {
+	File   string   `json:"file"`
+	Kind   api.Kind `json:"kind"`
+	Action string   `json:"action"` // create | update | noop | conflict
+	PostID string   `json:"post_id,omitempty"`
+
+	// FileHash guards against the Markdown file being edited after planning,
+	// RemoteUpdatedAt against the post being edited in Ghost.
+	FileHash        string `json:"file_hash"`
+	RemoteUpdatedAt string `json:"remote_updated_at,omitempty"`
+
+	Post     api.Post          `json:"post"`
+	Uploaded map[string]string `json:"uploaded,omitempty"` // image ref → URL
+	Pending  map[string]string `json:"pending,omitempty"`  // image ref → local path
+	Changes  []change          `json:"changes,omitempty"`
+	Conflict string            `json:"conflict,omitempty"`
+}
+
+func fileHash(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(raw)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// plan works out what publish would do to one file without writing
+// anything, to Ghost or to disk.
+func (p *publisher) plan(file string) (planEntry, error) {
+	e := planEntry{File: file}
+	var err error
+	if e.FileHash, err = fileHash(file); err != nil {
+		return e, err
+	}
+	src, err := loadSource(p.client, file)
+	if err != nil {
+		return e, err
+	}
+	e.Kind, e.PostID = src.Kind, src.Meta.PostID
+	if src.Meta.Hash == src.Hash {
+		e.Action = "noop"
+		return e, nil
+	}
+
+	dir := filepath.Dir(file)
+	e.Uploaded, e.Pending = p.images.Lookup(src.MD, dir)
+	meta := src.Meta
+	for _, f := range meta.ImageFields() {
+		p.images.LookupRef(*f.Value, dir, e.Uploaded, e.Pending)
+		if url, ok := e.Uploaded[*f.Value]; ok {
+			*f.Value = url
+		}
+	}
+	if e.Post, err = p.payload(src.File, meta, src.MD, e.Uploaded); err != nil {
+		return e, err
+	}
+
+	var remote api.Post
+	if src.Meta.PostID == "" {
+		e.Action = "create"
+	} else {
+		e.Action = "update"
+		if remote, err = p.client.GetPost(context.Background(), e.Kind, src.Meta.PostID); err != nil {
+			return e, err
+		}
+		e.RemoteUpdatedAt = remote.UpdatedAt
+	}
+	e.Changes = diffPost(remote, e.Post, e.Action == "update", p.ghostOwned)
+	if e.Action == "update" && len(e.Changes) == 0 {
+		e.Action = "noop"
+	}
+	if last := src.Meta.RemoteUpdatedAt; e.Action == "update" && last != "" &&
+		last != remote.UpdatedAt && p.merge != mergeLocal {
+		e.Action = "conflict"
+		e.Conflict = conflictError(file, src.Meta, &api.ConflictError{
+			Kind: e.Kind, ID: e.PostID, LastSeen: last, Current: remote,
+		}).Error()
+	}
+	return e, nil
+}
+
+// apply executes one planned entry: upload pending images, send the planned
+// payload and record the result in the file's front-matter.
+func (p *publisher) apply(e planEntry) (outcome, error) {
+	if e.Action == "noop" {
+		return outcomeSkipped, nil
+	}
+	src, err := loadSource(p.client, e.File)
+	if err != nil {
+		return outcomeFailed, err
+	}
+
+	mapping := make(map[string]string, len(e.Uploaded)+len(e.Pending))
+	for ref, url := range e.Uploaded {
+		mapping[ref] = url
+	}
+	for ref, path := range e.Pending {
+		url, err := p.images.Upload(path)
+		if err != nil {
+			return outcomeFailed, fmt.Errorf("uploading %s: %w", ref, err)
+		}
+		mapping[ref] = url
+	}
+	// The same render with the new URLs is the planned body with only the
+	// pending images swapped in.
+	post := e.Post
+	out, err := renderPost(p.client, e.File, src.Meta, src.MD, mapping)
+	if err != nil {
+		return outcomeFailed, err
+	}
+	post.HTML, post.Lexical = out.HTML, out.Lexical
+	for _, f := range []*string{&post.FeatureImage, &post.OGImage, &post.TwitterImage} {
+		if url, ok := mapping[*f]; ok {
+			*f = url
+		}
+	}
+
+	newID, err := api.UpsertWith(p.client, post, e.PostID, api.UpsertOptions{
+		Kind:       e.Kind,
+		LastSeen:   e.RemoteUpdatedAt,
+		GhostOwned: p.ghostOwned,
+	})
+	if err != nil {
+		return outcomeFailed, err
+	}
+	if _, err := p.record(src, newID); err != nil {
+		return outcomeFailed, err
+	}
+	if e.Action == "create" {
+		return outcomeCreated, nil
+	}
+	return outcomeUpdated, nil
+}
+
+// stale reports why a planned entry can no longer be applied as reviewed.
+func (p *publisher) stale(e planEntry) error {
+	switch e.Action {
+	case "noop":
+		return nil
+	case "conflict":
+		return errors.New(e.Conflict)
+	}
+	h, err := fileHash(e.File)
+	if err != nil {
+		return err
+	}
+	if h != e.FileHash {
+		return fmt.Errorf("file changed since the plan was made")
+	}
+	// The same file can still render differently, e.g. when a linked post
+	// has since been published or the config changed.
+	src, err := loadSource(p.client, e.File)
+	if err != nil {
+		return err
+	}
+	out, err := renderPost(p.client, e.File, src.Meta, src.MD, e.Uploaded)
+	if err != nil {
+		return err
+	}
+	if out.HTML != e.Post.HTML || out.Lexical != e.Post.Lexical {
+		return fmt.Errorf("post renders differently than when the plan was made")
+	}
+	if e.Action == "update" {
+		remote, err := p.client.GetPost(context.Background(), e.Kind, e.PostID)
+		if err != nil {
+			return err
+		}
+		if remote.UpdatedAt != e.RemoteUpdatedAt {
+			return fmt.Errorf("post changed in Ghost since the plan was made (updated_at %s → %s)",
+				e.RemoteUpdatedAt, remote.UpdatedAt)
+		}
+	}
+	return nil
+}
+
+func planCmd() *cobra.Command {
+	var out string
+	var concurrency int
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "plan [file|dir|glob]...",
+		Short: "Show what publish would change in Ghost",
+		Long: `Compare every post with its live version in Ghost and print a
+field-by-field diff. Nothing is uploaded or written. Use --out to save the
+plan for ghostpost apply.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			posts, err := findPosts(args)
+			if err != nil {
+				return err
+			}
+			p, err := newPublisher(context.Background())
+			if err != nil {
+				return err
+			}
+			if force {
+				p.merge = mergeLocal
+			}
+
+			pl := plan{Version: planVersion, APIURL: cfg.APIURL, Created: time.Now().UTC().Format(time.RFC3339)}
+			var mu sync.Mutex
+			entries := make(map[string]planEntry, len(posts))
+			results := runBatch(posts, concurrency, func(file string) (outcome, error) {
+				e, err := p.plan(file)
+				mu.Lock()
+				entries[file] = e
+				mu.Unlock()
+				return outcomeSkipped, err
+			})
+			for _, file := range posts {
+				pl.Entries = append(pl.Entries, entries[file])
+			}
+
+			counts := map[string]int{}
+			failed := 0
+			for i, e := range pl.Entries {
+				if err := results[i].Err; err != nil {
+					fmt.Printf("✗ %s: %v\n", e.File, err)
+					failed++
+					continue
+				}
+				counts[e.Action]++
+				switch e.Action {
+				case "noop":
+					continue
+				case "create":
+					fmt.Printf("+ create %s\n", e.File)
+				case "update":
+					fmt.Printf("~ update %s (%s)\n", e.File, e.PostID)
+				case "conflict":
+					fmt.Printf("! conflict %s (%s): %s\n", e.File, e.PostID, e.Conflict)
+				}
+				printChanges(e.Changes)
+				for ref, path := range e.Pending {
+					verb := "upload"
+					if !images.IsLocal(path) {
+						verb = "mirror"
+					}
+					fmt.Printf("    + %s %s\n", verb, ref)
+				}
+			}
+			fmt.Printf("\nPlan: %d to create, %d to update, %d unchanged.\n",
+				counts["create"], counts["update"], counts["noop"])
+			if n := counts["conflict"]; n > 0 {
+				fmt.Printf("%d post(s) were edited in Ghost since the last publish; pull them or plan with --force.\n", n)
+			}
+
+			if failed > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d of %d file(s) could not be planned", failed, len(posts))
+			}
+			if out != "" {
+				buf, err := json.MarshalIndent(pl, "", "  ")
+				if err != nil {
+					return err
+				}
+				if err := os.WriteFile(out, append(buf, '\n'), 0o644); err != nil {
+					return err
+				}
+				fmt.Printf("Saved plan to %s. Run `ghostpost apply %s` to execute it.\n", out, out)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&out, "out", "o", "", "Save the plan to this file")
+	cmd.Flags().BoolVar(&force, "force", false, "Plan to overwrite posts edited in Ghost since the last publish")
+	cmd.Flags().IntVarP(&concurrency, "concurrency", "j", 4, "Number of posts to compare in parallel")
+	return cmd
+}
+
+func applyCmd() *cobra.Command {
+	var concurrency int
+
+	cmd := &cobra.Command{
+		Use:   "apply <plan.json>",
+		Short: "Execute a plan saved by ghostpost plan",
+		Long: `Execute exactly the changes in a saved plan. Nothing is applied if any
+planned file was edited, or any planned post was changed in Ghost, after
+the plan was made.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			raw, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			var pl plan
+			if err := json.Unmarshal(raw, &pl); err != nil {
+				return fmt.Errorf("%s: %w", args[0], err)
+			}
+			if pl.Version != planVersion {
+				return fmt.Errorf("%s: unsupported plan version %d", args[0], pl.Version)
+			}
+			if pl.APIURL != cfg.APIURL {
+				return fmt.Errorf("plan was made for %s, not %s", pl.APIURL, cfg.APIURL)
+			}
+
+			p, err := newPublisher(context.Background())
+			if err != nil {
+				return err
+			}
+
+			// Check everything before touching anything.
+			byFile := make(map[string]planEntry, len(pl.Entries))
+			files := make([]string, len(pl.Entries))
+			for i, e := range pl.Entries {
+				byFile[e.File] = e
+				files[i] = e.File
+			}
+			checks := runBatch(files, concurrency, func(file string) (outcome, error) {
+				return outcomeSkipped, p.stale(byFile[file])
+			})
+			stale := 0
+			for _, c := range checks {
+				if c.Err != nil {
+					fmt.Printf("✗ %s: %v\n", c.File, c.Err)
+					stale++
+				}
+			}
+			if stale > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("refusing to apply: %d planned file(s) are out of date, run ghostpost plan again", stale)
+			}
+
+			results := runBatch(files, concurrency, func(file string) (outcome, error) {
+				return p.apply(byFile[file])
+			})
+			if failed := printSummary(results); failed > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d of %d file(s) failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&concurrency, "concurrency", "j", 4, "Number of posts to apply in parallel")
+	return cmd
+}
@@ -0,0 +1,132 @@
+// cmd/ghostpost/plan_test.go
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/api"
+)
+
+func TestPlanSavesAndApplies(t *testing.T) {
+	p, g := newTestPublisher(t)
+	writePost(t, "posts/new.md", "---\ntitle: New\nstatus: published\n---\nHello ![pic](pic.png)\n")
+	writePost(t, "posts/pic.png", string(testPNG(t)))
+
+	e, err := p.plan("posts/new.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Action != "create" || e.Pending["pic.png"] == "" || len(e.Uploaded) != 0 {
+		t.Fatalf("plan = %+v, want a create with pic.png pending", e)
+	}
+
+	pl := plan{Version: planVersion, APIURL: cfg.APIURL, Entries: []planEntry{e}}
+	raw, err := json.Marshal(pl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded plan
+	if err := json.Unmarshal(raw, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := json.Marshal(loaded); !bytes.Equal(again, raw) {
+		t.Errorf("plan changed when loaded:\n got %s\nwant %s", again, raw)
+	}
+
+	if err := p.stale(loaded.Entries[0]); err != nil {
+		t.Fatalf("stale: %v", err)
+	}
+	if o, err := p.apply(loaded.Entries[0]); err != nil || o != outcomeCreated {
+		t.Fatalf("apply = %v, %v", o, err)
+	}
+	meta, _, _ := parseFile("posts/new.md")
+	post := g.Get(meta.PostID)
+	if !strings.Contains(post.HTML, g.URL+"/content/images/pic.png") {
+		t.Errorf("applied HTML %q doesn't use the uploaded image", post.HTML)
+	}
+
+	again, err := p.plan("posts/new.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Action != "noop" {
+		t.Errorf("plan after apply = %s, want noop", again.Action)
+	}
+}
+
+func TestStale(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, g *fakeGhost, id string)
+		want   string // "" for still applicable
+	}{
+		{"unchanged", func(*testing.T, *fakeGhost, string) {}, ""},
+		{"file edited", func(t *testing.T, _ *fakeGhost, _ string) {
+			raw, _ := os.ReadFile("posts/a.md")
+			writePost(t, "posts/a.md", string(raw)+"One more line.\n")
+		}, "file changed since the plan was made"},
+		{"renders differently", func(*testing.T, *fakeGhost, string) {
+			cfg.Renderer = "lexical"
+		}, "post renders differently"},
+		{"edited in Ghost", func(_ *testing.T, g *fakeGhost, id string) {
+			g.Edit(id, func(p *api.Post) { p.Title = "Changed in Ghost" })
+		}, "post changed in Ghost since the plan was made"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, g := newTestPublisher(t)
+			id := published(t, p, "posts/a.md", "---\ntitle: A\n---\nFirst.\n")
+			raw, _ := os.ReadFile("posts/a.md")
+			writePost(t, "posts/a.md", strings.Replace(string(raw), "First.", "Second.", 1))
+
+			e, err := p.plan("posts/a.md")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Action != "update" {
+				t.Fatalf("action = %s, want update", e.Action)
+			}
+			tt.change(t, g, id)
+
+			err = p.stale(e)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("stale = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("stale = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanConflict(t *testing.T) {
+	p, g := newTestPublisher(t)
+	id := published(t, p, "posts/a.md", "---\ntitle: A\n---\nFirst.\n")
+	g.Edit(id, func(p *api.Post) { p.HTML = "<p>Edited in Ghost.</p>" })
+	raw, _ := os.ReadFile("posts/a.md")
+	writePost(t, "posts/a.md", strings.Replace(string(raw), "First.", "Second.", 1))
+
+	e, err := p.plan("posts/a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Action != "conflict" || e.Conflict == "" {
+		t.Fatalf("plan = %s %q, want a conflict", e.Action, e.Conflict)
+	}
+	if err := p.stale(e); err == nil || err.Error() != e.Conflict {
+		t.Errorf("stale = %v, want the conflict", err)
+	}
+
+	p.merge = mergeLocal // plan --force
+	if e, err = p.plan("posts/a.md"); err != nil || e.Action != "update" {
+		t.Errorf("plan --force = %s, %v, want update", e.Action, err)
+	}
+}
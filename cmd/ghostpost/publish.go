@@ -7,8 +7,10 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -16,13 +18,33 @@ import (
 	"github.com/rodchristiansen/ghost-gitops-publishing/internal/api"
 	"github.com/rodchristiansen/ghost-gitops-publishing/internal/frontmatter"
 	"github.com/rodchristiansen/ghost-gitops-publishing/internal/images"
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/render"
 
 	"github.com/spf13/cobra"
-	"github.com/yuin/goldmark"
 )
 
 var httpClient = &http.Client{Timeout: 30 * time.Second}
 
+// ownableFields are the keys the ghost_owned setting accepts: front-matter
+// keys, plus html for the body.
+var ownableFields = map[string]bool{
+	"title": true, "slug": true, "status": true, "published_at": true,
+	"visibility": true, "tiers": true, "featured": true, "custom_excerpt": true,
+	"authors": true, "custom_template": true, "feature_image": true, "tags": true,
+	"og_image": true, "twitter_image": true, "html": true,
+}
+
+// Conflict strategies for posts edited in Ghost since the last publish.
+const (
+	mergeLocal  = "local"  // overwrite Ghost with the Markdown file
+	mergeRemote = "remote" // keep Ghost's version and pull it into the file
+)
+
+func htmlHash(html string) string {
+	h := sha256.Sum256([]byte(html))
+	return hex.EncodeToString(h[:])
+}
+
 func defaultStatus(s string) string {
 	if s == "" {
 		return "draft"
@@ -30,172 +52,454 @@ func defaultStatus(s string) string {
 	return s
 }
 
-func publishCmd() *cobra.Command {
-	var file string
-	var openEditor bool
+// publisher holds everything that can be shared between posts in a batch:
+// one API client, one image cache and one author/tier lookup.
+type publisher struct {
+	client     *api.Client
+	images     *images.Service
+	openEditor bool
+	merge      string // "" (refuse), mergeLocal or mergeRemote on conflict
+	ghostOwned []string
 
-	cmd := &cobra.Command{
-		Use:   "publish",
-		Short: "Push Markdown → Ghost",
-		RunE: func(_ *cobra.Command, _ []string) error {
-			meta, md, err := frontmatter.ParseFile(file)
-			if err != nil {
-				return err
-			}
+	authorIDs   map[string]string // name → ID, nil if Ghost couldn't be asked
+	tiersByName map[string]api.TierRef
+	tiersBySlug map[string]api.TierRef
+}
 
-			// Compute SHA256 digest of Markdown body
-			h := sha256.Sum256(md)
-			nowHash := hex.EncodeToString(h[:])
+func newPublisher(ctx context.Context) (*publisher, error) {
+	imgs, err := newImages()
+	if err != nil {
+		return nil, err
+	}
+	p := &publisher{
+		client:     api.New(cfg.APIURL, cfg.AdminJWT),
+		images:     imgs,
+		ghostOwned: cfg.GhostOwned,
+	}
+	for _, k := range p.ghostOwned {
+		if !ownableFields[k] {
+			return nil, fmt.Errorf("ghost_owned: unknown field %q (want one of %v)", k, keys(ownableFields))
+		}
+	}
 
-			// If hash matches, skip publishing
-			if meta.Hash == nowHash {
-				fmt.Println("↻ no changes since last publish, skipping…")
-				return nil
-			}
+	// Map author names to IDs with error handling
+	allAuthors, err := p.client.ListAuthors(ctx)
+	if err != nil {
+		fmt.Println("warning: could not fetch authors from Ghost, using names as IDs")
+	} else {
+		p.authorIDs = make(map[string]string, len(allAuthors))
+		for _, a := range allAuthors {
+			p.authorIDs[a.Name] = a.ID
+		}
+	}
 
-			imgSvc := images.New(cfg.APIURL, cfg.AdminJWT, httpClient)
-			md, _ = imgSvc.Rewrite(md, filepath.Dir(file))
+	// Map tier names/slugs to TierRef (ID+Name+Slug)
+	allTiers, err := p.client.ListTiers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch tiers: %w", err)
+	}
+	p.tiersByName = make(map[string]api.TierRef, len(allTiers))
+	p.tiersBySlug = make(map[string]api.TierRef, len(allTiers))
+	for _, t := range allTiers {
+		p.tiersByName[t.Name] = t
+		p.tiersBySlug[t.Slug] = t
+	}
+	return p, nil
+}
 
-			var html bytes.Buffer
-			if err := goldmark.Convert(md, &html); err != nil {
-				return err
-			}
+func (p *publisher) authors(names []string) []string {
+	if p.authorIDs == nil {
+		return names
+	}
+	var ids []string
+	for _, name := range names {
+		if id, ok := p.authorIDs[name]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
 
-			client := api.New(cfg.APIURL, cfg.AdminJWT)
+func (p *publisher) tiers(wants []string) ([]api.TierRef, error) {
+	var refs []api.TierRef
+	for _, want := range wants {
+		if t, ok := p.tiersByName[want]; ok {
+			refs = append(refs, t)
+		} else if t, ok := p.tiersBySlug[want]; ok {
+			refs = append(refs, t)
+		} else {
+			return nil, fmt.Errorf("unknown tier %q (available: %v)", want, keys(p.tiersByName))
+		}
+	}
+	return refs, nil
+}
 
-			// Map author names to IDs with error handling
-			allAuthors, err := client.ListAuthors(context.Background())
-			var authorIDs []string
-			if err != nil {
-				fmt.Println("warning: could not fetch authors from Ghost, using names as IDs")
-				authorIDs = meta.Authors
-			} else {
-				nameToAuthorID := map[string]string{}
-				for _, a := range allAuthors {
-					nameToAuthorID[a.Name] = a.ID
-				}
-				for _, name := range meta.Authors {
-					if id, ok := nameToAuthorID[name]; ok {
-						authorIDs = append(authorIDs, id)
-					}
-				}
-			}
+// source is a parsed Markdown file and its fingerprint.
+type source struct {
+	File   string
+	Kind   api.Kind
+	Meta   frontmatter.Meta
+	MD     []byte
+	Body   string  // MD rendered as-is, with local image paths
+	Assets []asset // local files MD and Meta refer to
+	Hash   string  // fingerprint of all of the above
+}
 
-			// Map tier names/slugs to TierRef (ID+Name+Slug)
-			allTiers, err := client.ListTiers(context.Background())
-			if err != nil {
-				return fmt.Errorf("could not fetch tiers: %w", err)
-			}
-			byName := make(map[string]api.TierRef, len(allTiers))
-			bySlug := make(map[string]api.TierRef, len(allTiers))
-			for _, t := range allTiers {
-				byName[t.Name] = t
-				bySlug[t.Slug] = t
-			}
-			var tierRefs []api.TierRef
-			for _, want := range meta.Tiers {
-				if t, ok := byName[want]; ok {
-					tierRefs = append(tierRefs, t)
-				} else if t, ok := bySlug[want]; ok {
-					tierRefs = append(tierRefs, t)
-				} else {
-					return fmt.Errorf("unknown tier %q (available: %v)", want, keys(byName))
-				}
-			}
+func loadSource(client *api.Client, file string) (source, error) {
+	meta, md, err := parseFile(file)
+	if err != nil {
+		return source{}, err
+	}
+	kind, err := kindOf(file, meta)
+	if err != nil {
+		return source{}, err
+	}
+	out, err := renderPost(client, file, meta, md, nil)
+	if err != nil {
+		return source{}, located(file, md, err)
+	}
+	assets := localAssets(meta, md, filepath.Dir(file))
+	return source{
+		File:   file,
+		Kind:   kind,
+		Meta:   meta,
+		MD:     md,
+		Body:   out.Body(),
+		Assets: assets,
+		Hash:   fingerprint(meta, out.Body(), assets),
+	}, nil
+}
 
-			post := api.Post{
-				Title:          meta.Title,
-				Slug:           meta.Slug,
-				Status:         defaultStatus(meta.Status),
-				HTML:           html.String(),
-				FeatureImage:   meta.FeatureImage,
-				Tags:           api.WrapTags(meta.Tags),
-				CustomExcerpt:  meta.CustomExcerpt,
-				PublishedAt:    meta.PublishedAt,
-				Visibility:     meta.Visibility,
-				Tiers:          api.WrapTiers(tierRefs),
-				Featured:       meta.Featured,
-				Authors:        api.WrapAuthors(authorIDs),
-				CustomTemplate: meta.CustomTemplate,
+// kindOf decides whether file is a Ghost post or page: `type: page` in the
+// front-matter, or else living under a pages/ directory.
+func kindOf(file string, meta frontmatter.Meta) (api.Kind, error) {
+	switch meta.Type {
+	case "post":
+		return api.KindPost, nil
+	case "page":
+		return api.KindPage, nil
+	case "":
+		for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(file)), "/") {
+			if dir == "pages" {
+				return api.KindPage, nil
 			}
-			newID, err := api.Upsert(client, post, meta.PostID)
+		}
+		return api.KindPost, nil
+	}
+	return "", fmt.Errorf("unknown type %q (want post or page)", meta.Type)
+}
+
+// renderPost renders md, from the post in file whose images were uploaded
+// to urls, with the configured renderer and the Markdown extensions the
+// site and then meta switch on. Links to other posts point at Ghost.
+func renderPost(client *api.Client, file string, meta frontmatter.Meta, md []byte, urls map[string]string) (render.Output, error) {
+	r, err := render.New(cfg.Renderer)
+	if err != nil {
+		return render.Output{}, err
+	}
+	ext := render.Extensions(cfg.Markdown).With(meta.Markdown)
+	if err := ext.Check(); err != nil {
+		return render.Output{}, err
+	}
+	return r.Render(md, render.Options{
+		Dir:        filepath.Dir(file),
+		URLs:       urls,
+		MaxWidth:   cfg.Images.MaxWidth,
+		Limits:     uploadLimits(),
+		Extensions: ext,
+		Highlight:  render.Highlight(cfg.Highlight),
+		Links:      postLinks(client, file),
+	})
+}
+
+// located makes the line numbers in err's render errors count from the
+// top of file, front-matter included, and lists them all.
+func located(file string, md []byte, err error) error {
+	errs := []error{err}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = j.Unwrap()
+	}
+	raw, _ := os.ReadFile(file)
+	offset := bytes.Count(raw, []byte("\n")) - bytes.Count(md, []byte("\n"))
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		var re *render.Error
+		if errors.As(e, &re) && offset > 0 {
+			e = &render.Error{Line: re.Line + offset, Msg: re.Msg}
+		}
+		msgs = append(msgs, e.Error())
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// payload renders md, from the post in file, with images pointing at
+// urls, and maps meta onto the Ghost post that would be sent.
+func (p *publisher) payload(file string, meta frontmatter.Meta, md []byte, urls map[string]string) (api.Post, error) {
+	out, err := renderPost(p.client, file, meta, md, urls)
+	if err != nil {
+		return api.Post{}, err
+	}
+
+	tierRefs, err := p.tiers(meta.Tiers)
+	if err != nil {
+		return api.Post{}, err
+	}
+
+	return api.Post{
+		Title:          meta.Title,
+		Slug:           meta.Slug,
+		Status:         defaultStatus(meta.Status),
+		HTML:           out.HTML,
+		Lexical:        out.Lexical,
+		FeatureImage:   meta.FeatureImage,
+		OGImage:        meta.OGImage,
+		TwitterImage:   meta.TwitterImage,
+		Tags:           api.WrapTags(meta.Tags),
+		CustomExcerpt:  meta.CustomExcerpt,
+		PublishedAt:    meta.PublishedAt,
+		Visibility:     meta.Visibility,
+		Tiers:          api.WrapTiers(tierRefs),
+		Featured:       meta.Featured,
+		Authors:        api.WrapAuthors(p.authors(meta.Authors)),
+		CustomTemplate: meta.CustomTemplate,
+	}, nil
+}
+
+// publish pushes a single Markdown file to Ghost and writes the resulting
+// state back into its front-matter.
+func (p *publisher) publish(file string) (outcome, error) {
+	src, err := loadSource(p.client, file)
+	if err != nil {
+		return outcomeFailed, err
+	}
+
+	// If nothing that ends up in Ghost changed, skip publishing
+	if src.Meta.Hash == src.Hash {
+		return outcomeSkipped, nil
+	}
+
+	dir := filepath.Dir(file)
+	urls, err := p.images.UploadAll(src.MD, dir)
+	if err != nil {
+		return outcomeFailed, err
+	}
+	meta := src.Meta // keeps the local image paths for the file
+	for _, f := range meta.ImageFields() {
+		path := p.images.Source(*f.Value, dir)
+		if path == "" {
+			continue
+		}
+		url, err := p.images.Upload(path)
+		if err != nil {
+			return outcomeFailed, fmt.Errorf("%s: uploading %s: %w", f.Key, *f.Value, err)
+		}
+		*f.Value = url
+	}
+	post, err := p.payload(src.File, meta, src.MD, urls)
+	if err != nil {
+		return outcomeFailed, err
+	}
+	lastSeen := src.Meta.RemoteUpdatedAt
+	if p.merge == mergeLocal {
+		lastSeen = ""
+	}
+	newID, err := api.UpsertWith(p.client, post, src.Meta.PostID, api.UpsertOptions{
+		Kind:       src.Kind,
+		LastSeen:   lastSeen,
+		GhostOwned: p.ghostOwned,
+	})
+	var conflict *api.ConflictError
+	if errors.As(err, &conflict) && p.merge == mergeRemote {
+		o, err := writePulled(p.client, p.images, file, src.Meta, src.MD, conflict.Current)
+		if o == outcomeUpdated {
+			o = outcomePulled
+		}
+		return o, err
+	}
+	if conflict != nil {
+		return outcomeFailed, conflictError(file, src.Meta, conflict)
+	}
+	if err != nil {
+		return outcomeFailed, err
+	}
+	result := outcomeUpdated
+	if src.Meta.PostID == "" {
+		result = outcomeCreated
+	}
+
+	if meta, err = p.record(src, newID); err != nil {
+		return outcomeFailed, err
+	}
+
+	if p.openEditor {
+		url := fmt.Sprintf("%s/ghost/#/editor/%s/%s", siteRoot(), src.Kind.Singular(), meta.PostID)
+		_ = launchBrowser(url)
+	}
+	return result, nil
+}
+
+// record refreshes the post from Ghost and writes the ID, fingerprint and
+// any server-side values back into the file's front-matter. The body is
+// written back untouched, local image paths and all.
+//
+// With a sidecar state file, the file isn't touched at all: the ID,
+// fingerprint and image URLs go to the state file instead.
+func (p *publisher) record(src source, newID string) (frontmatter.Meta, error) {
+	meta := src.Meta
+
+	// Always refresh the post from Ghost so we get the real published_at + status
+	ghostPost, err := p.client.GetPost(context.Background(), src.Kind, newID)
+	if err != nil {
+		return meta, err
+	}
+
+	if sidecar != nil {
+		meta.PostID = newID
+		meta.Hash = src.Hash // fingerprint what the file says, not what Ghost filled in
+		meta.RemoteUpdatedAt = ghostPost.UpdatedAt
+		meta.RemoteHash = htmlHash(ghostPost.HTML)
+		dir := filepath.Dir(src.File)
+		uploaded, _ := p.images.Lookup(src.MD, dir)
+		for _, f := range meta.ImageFields() {
+			p.images.LookupRef(*f.Value, dir, uploaded, map[string]string{})
+		}
+		return meta, putState(src.File, meta, uploaded)
+	}
+
+	dirty := false
+	if meta.PostID == "" {
+		meta.PostID = newID
+		dirty = true
+	}
+	if meta.PublishedAt != ghostPost.PublishedAt {
+		meta.PublishedAt = ghostPost.PublishedAt
+		dirty = true
+	}
+	if meta.Status != ghostPost.Status {
+		meta.Status = ghostPost.Status
+		dirty = true
+	}
+	// update meta.Authors with human-readable names from ghostPost
+	var newAuthors []string
+	for _, a := range ghostPost.Authors {
+		newAuthors = append(newAuthors, a.Name)
+	}
+	if !api.EqualStringSlices(meta.Authors, newAuthors) {
+		meta.Authors = newAuthors
+		dirty = true
+	}
+	// update meta.Tiers with human-readable names from ghostPost
+	var newTiers []string
+	for _, t := range ghostPost.Tiers {
+		newTiers = append(newTiers, t.Name)
+	}
+	if !api.EqualStringSlices(meta.Tiers, newTiers) {
+		meta.Tiers = newTiers
+		dirty = true
+	}
+	// Remember what Ghost looks like now, to spot edits made there later
+	if meta.RemoteUpdatedAt != ghostPost.UpdatedAt {
+		meta.RemoteUpdatedAt = ghostPost.UpdatedAt
+		dirty = true
+	}
+	if remoteHash := htmlHash(ghostPost.HTML); meta.RemoteHash != remoteHash {
+		meta.RemoteHash = remoteHash
+		dirty = true
+	}
+	// Always update the fingerprint after publish. It covers the values
+	// written back above, so they don't count as a change next time.
+	if hash := fingerprint(meta, src.Body, src.Assets); meta.Hash != hash {
+		meta.Hash = hash
+		dirty = true
+	}
+	if dirty {
+		if err := frontmatter.WriteFile(src.File, meta, src.MD); err != nil {
+			return meta, err
+		}
+	}
+	return meta, nil
+}
+
+// conflictError explains how a post was edited in Ghost and what to do
+// about it.
+func conflictError(file string, meta frontmatter.Meta, c *api.ConflictError) error {
+	what := "metadata changed in Ghost, body unchanged"
+	if htmlHash(c.Current.HTML) != meta.RemoteHash {
+		what = "body edited in Ghost"
+	}
+	return fmt.Errorf("conflict: %s since last publish (updated_at %s → %s); "+
+		"run `ghostpost pull %s` to import it, or publish with --force or --merge=local|remote",
+		what, c.LastSeen, c.Current.UpdatedAt, file)
+}
+
+func publishCmd() *cobra.Command {
+	var files []string
+	var openEditor bool
+	var concurrency int
+	var force bool
+	var merge string
+
+	cmd := &cobra.Command{
+		Use:   "publish [file|dir|glob]...",
+		Short: "Push Markdown → Ghost",
+		Long: `Push one or more Markdown posts to Ghost.
+
+Arguments may be files, directories (searched recursively for .md files)
+or glob patterns. Files with "type: page" in their front-matter, or under
+a pages/ directory, are published as Ghost pages. Posts are published
+concurrently and share one API client, one author/tier lookup and one
+image cache.
+
+A post edited in Ghost since the last publish is a conflict and is not
+overwritten, unless --force or a --merge strategy says how to resolve it:
+"local" overwrites Ghost, "remote" pulls Ghost's version into the file.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			posts, err := findPosts(append(files, args...))
 			if err != nil {
 				return err
 			}
+			if len(posts) == 0 {
+				return fmt.Errorf("no Markdown files given (use --file or pass paths)")
+			}
 
-			// Always refresh the post from Ghost so we get the real published_at + status
-			ghostPost, err := client.GetPost(context.Background(), newID)
+			p, err := newPublisher(context.Background())
 			if err != nil {
 				return err
 			}
-
-			dirty := false
-			if meta.PostID == "" {
-				meta.PostID = newID
-				dirty = true
-			}
-			if meta.PublishedAt != ghostPost.PublishedAt {
-				meta.PublishedAt = ghostPost.PublishedAt
-				dirty = true
-			}
-			if meta.Status != ghostPost.Status {
-				meta.Status = ghostPost.Status
-				dirty = true
-			}
-			// update meta.Authors with human-readable names from ghostPost
-			var newAuthors []string
-			for _, a := range ghostPost.Authors {
-				newAuthors = append(newAuthors, a.Name)
-			}
-			if len(meta.Authors) != len(newAuthors) {
-				meta.Authors = newAuthors
-				dirty = true
-			} else {
-				for i := range meta.Authors {
-					if meta.Authors[i] != newAuthors[i] {
-						meta.Authors = newAuthors
-						dirty = true
-						break
-					}
-				}
-			}
-			// update meta.Tiers with human-readable names from ghostPost
-			var newTiers []string
-			for _, t := range ghostPost.Tiers {
-				newTiers = append(newTiers, t.Name)
-			}
-			if !api.EqualStringSlices(meta.Tiers, newTiers) {
-				meta.Tiers = newTiers
-				dirty = true
-			}
-			// Always update hash after publish
-			if meta.Hash != nowHash {
-				meta.Hash = nowHash
-				dirty = true
-			}
-			if dirty {
-				if err := frontmatter.WriteFile(file, meta, md); err != nil {
-					return err
-				}
+			p.openEditor = openEditor
+			if p.merge, err = mergeStrategy(merge, force); err != nil {
+				return err
 			}
 
-			if openEditor {
-				// strip trailing "/ghost/api/admin/" → siteRoot
-				siteRoot := strings.Split(cfg.APIURL, "/ghost/")[0]
-				url := fmt.Sprintf("%s/ghost/#/editor/post/%s", siteRoot, meta.PostID)
-				_ = launchBrowser(url)
+			results := runBatch(posts, concurrency, p.publish)
+			if failed := printSummary(results); failed > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d of %d file(s) failed", failed, len(results))
 			}
 			return nil
-
 		},
 	}
 
-	cmd.Flags().StringVarP(&file, "file", "f", "", "Markdown file")
-	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringSliceVarP(&files, "file", "f", nil, "Markdown file (repeatable)")
 	cmd.Flags().BoolVarP(&openEditor, "editor", "e", false, "Open post in Ghost editor")
+	cmd.Flags().IntVarP(&concurrency, "concurrency", "j", 4, "Number of posts to publish in parallel")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite posts edited in Ghost since the last publish")
+	cmd.Flags().StringVar(&merge, "merge", "", "Resolve conflicts with Ghost edits: local | remote")
 	return cmd
 }
 
+func mergeStrategy(merge string, force bool) (string, error) {
+	switch {
+	case force && merge != "" && merge != mergeLocal:
+		return "", fmt.Errorf("--force conflicts with --merge=%s", merge)
+	case force:
+		return mergeLocal, nil
+	case merge == "", merge == mergeLocal, merge == mergeRemote:
+		return merge, nil
+	}
+	return "", fmt.Errorf("unknown --merge strategy %q (want local or remote)", merge)
+}
+
 // Helper to list keys for error messages
 func keys[K comparable, V any](m map[K]V) []K {
 	out := make([]K, 0, len(m))
@@ -0,0 +1,98 @@
+// cmd/ghostpost/publish_test.go
+
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/api"
+)
+
+func TestMergeStrategy(t *testing.T) {
+	tests := []struct {
+		merge   string
+		force   bool
+		want    string
+		wantErr string
+	}{
+		{"", false, "", ""},
+		{"local", false, mergeLocal, ""},
+		{"remote", false, mergeRemote, ""},
+		{"", true, mergeLocal, ""},
+		{"local", true, mergeLocal, ""},
+		{"remote", true, "", "--force conflicts with --merge=remote"},
+		{"theirs", false, "", `unknown --merge strategy "theirs"`},
+	}
+	for _, tt := range tests {
+		got, err := mergeStrategy(tt.merge, tt.force)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("mergeStrategy(%q, %v) error = %v, want %q", tt.merge, tt.force, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("mergeStrategy(%q, %v) = %q, %v, want %q", tt.merge, tt.force, got, err, tt.want)
+		}
+	}
+}
+
+func TestPublishConflict(t *testing.T) {
+	tests := []struct {
+		merge       string
+		want        outcome
+		wantErr     string
+		wantGhost   string // in the post's HTML in Ghost afterwards
+		wantFile    string // in the Markdown file afterwards
+		notWantFile string
+	}{
+		{"", outcomeFailed, "conflict", "Edited in Ghost.", "Edited locally.", "Edited in Ghost."},
+		{mergeLocal, outcomeUpdated, "", "Edited locally.", "Edited locally.", "Edited in Ghost."},
+		{mergeRemote, outcomePulled, "", "Edited in Ghost.", "Edited in Ghost.", "Edited locally."},
+	}
+	for _, tt := range tests {
+		t.Run("merge="+tt.merge, func(t *testing.T) {
+			p, g := newTestPublisher(t)
+			id := published(t, p, "posts/a.md", "---\ntitle: A\n---\nFirst.\n")
+			g.Edit(id, func(p *api.Post) { p.HTML = "<p>Edited in Ghost.</p>" })
+			raw, _ := os.ReadFile("posts/a.md")
+			writePost(t, "posts/a.md", strings.Replace(string(raw), "First.", "Edited locally.", 1))
+
+			p.merge = tt.merge
+			o, err := p.publish("posts/a.md")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("publish error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("publish: %v", err)
+			}
+			if o != tt.want {
+				t.Errorf("outcome = %v, want %v", o, tt.want)
+			}
+			if html := g.Get(id).HTML; !strings.Contains(html, tt.wantGhost) {
+				t.Errorf("Ghost has %q, want %q", html, tt.wantGhost)
+			}
+			raw, _ = os.ReadFile("posts/a.md")
+			if !strings.Contains(string(raw), tt.wantFile) || strings.Contains(string(raw), tt.notWantFile) {
+				t.Errorf("file is\n%s\nwant %q and not %q", raw, tt.wantFile, tt.notWantFile)
+			}
+		})
+	}
+}
+
+func TestPublishNoConflictAfterOwnUpdate(t *testing.T) {
+	p, _ := newTestPublisher(t)
+	published(t, p, "posts/a.md", "---\ntitle: A\n---\nFirst.\n")
+	for _, body := range []string{"Second.", "Third."} {
+		raw, _ := os.ReadFile("posts/a.md")
+		text := string(raw)
+		text = text[:strings.LastIndex(text, "---\n")+4] + body + "\n"
+		writePost(t, "posts/a.md", text)
+		if o, err := p.publish("posts/a.md"); err != nil || o != outcomeUpdated {
+			t.Fatalf("publish %s = %v, %v, want updated", body, o, err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// cmd/ghostpost/pull.go
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/api"
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/frontmatter"
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/htmlmd"
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/images"
+
+	"github.com/spf13/cobra"
+)
+
+// metaFromPost copies everything Ghost knows about a post into meta.
+// A local image path is kept while Ghost still shows what it was uploaded
+// to (uploaded maps local refs to URLs); an image changed or cleared in
+// Ghost replaces it.
+func metaFromPost(meta *frontmatter.Meta, post api.Post, uploaded map[string]string) {
+	meta.Title = post.Title
+	meta.Slug = post.Slug
+	meta.Status = post.Status
+	meta.PublishedAt = post.PublishedAt
+	meta.Visibility = post.Visibility
+	meta.Featured = post.Featured
+	meta.CustomExcerpt = post.CustomExcerpt
+	meta.CustomTemplate = post.CustomTemplate
+	meta.PostID = post.ID
+	remote := map[string]string{
+		"feature_image": post.FeatureImage,
+		"og_image":      post.OGImage,
+		"twitter_image": post.TwitterImage,
+	}
+	for _, f := range meta.ImageFields() {
+		local, url := *f.Value, remote[f.Key]
+		switch {
+		case !images.IsLocal(local), url == "":
+			*f.Value = url
+		case uploaded[local] != "" && uploaded[local] != url:
+			*f.Value = url // replaced in Ghost
+		}
+	}
+	meta.Tags = tagNames(post.Tags)
+	meta.Authors = nil
+	for _, a := range post.Authors {
+		meta.Authors = append(meta.Authors, a.Name)
+	}
+	meta.Tiers = tierNames(post.Tiers)
+	meta.RemoteUpdatedAt = post.UpdatedAt
+	meta.RemoteHash = htmlHash(post.HTML)
+}
+
+// uploadedImages finds the URLs the local image fields of meta were last
+// uploaded to, from the state file or else the image cache.
+func uploadedImages(imgs *images.Service, file string, meta frontmatter.Meta) map[string]string {
+	uploaded := map[string]string{}
+	if sidecar != nil {
+		if e, ok := sidecar.Get(file, meta.Slug); ok {
+			maps.Copy(uploaded, e.Images)
+		}
+	}
+	for _, f := range meta.ImageFields() {
+		if _, ok := uploaded[*f.Value]; !ok && imgs != nil && images.IsLocal(*f.Value) {
+			imgs.LookupRef(*f.Value, filepath.Dir(file), uploaded, map[string]string{})
+		}
+	}
+	return uploaded
+}
+
+// pullFile overwrites a published file with its current state in Ghost.
+func pullFile(client *api.Client, imgs *images.Service, file string) (outcome, error) {
+	src, err := loadSource(client, file)
+	if err != nil {
+		return outcomeFailed, err
+	}
+	if src.Meta.PostID == "" {
+		return outcomeSkipped, nil // never published, nothing to pull
+	}
+	post, err := client.GetPost(context.Background(), src.Kind, src.Meta.PostID)
+	if err != nil {
+		return outcomeFailed, err
+	}
+	return writePulled(client, imgs, file, src.Meta, src.MD, post)
+}
+
+// writePulled writes post into file. The body comes from the post's
+// Lexical document where it has a Markdown form, and from its HTML
+// otherwise.
+func writePulled(client *api.Client, imgs *images.Service, file string, meta frontmatter.Meta, old []byte, post api.Post) (outcome, error) {
+	md, ok := "", false
+	if post.Lexical != "" {
+		var err error
+		if md, ok, err = htmlmd.FromLexical(post.Lexical); err != nil {
+			return outcomeFailed, err
+		}
+	}
+	if !ok {
+		var err error
+		if md, err = htmlmd.Convert(post.HTML); err != nil {
+			return outcomeFailed, err
+		}
+	}
+	body := []byte(md)
+	// the file is written, and its fingerprint taken, from the meta with
+	// Ghost's values merged in
+	updated := meta
+	metaFromPost(&updated, post, uploadedImages(imgs, file, meta))
+	out, err := renderPost(client, file, updated, body, nil)
+	if err != nil {
+		return outcomeFailed, err
+	}
+	updated.Hash = fingerprint(updated, out.Body(), localAssets(updated, body, filepath.Dir(file)))
+	if reflect.DeepEqual(updated, meta) && bytes.Equal(bytes.TrimLeft(old, "\n"), bytes.TrimLeft(body, "\n")) {
+		return outcomeSkipped, nil
+	}
+	if sidecar != nil {
+		if err := putState(file, updated, nil); err != nil {
+			return outcomeFailed, err
+		}
+		updated = withoutState(updated)
+	}
+	if err := frontmatter.WriteFile(file, updated, body); err != nil {
+		return outcomeFailed, err
+	}
+	if old == nil {
+		return outcomeCreated, nil
+	}
+	return outcomeUpdated, nil
+}
+
+// exportAll writes every post that no file in dir tracks yet to
+// dir/<slug>.md, and every such page to dir/pages/<slug>.md.
+func exportAll(client *api.Client, dir string) ([]result, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	existing, err := findPosts([]string{dir})
+	if err != nil {
+		return nil, err
+	}
+	tracked := map[string]bool{}
+	for _, f := range existing {
+		if meta, _, err := parseFile(f); err == nil && meta.PostID != "" {
+			tracked[meta.PostID] = true
+		}
+	}
+
+	var results []result
+	for _, kind := range []api.Kind{api.KindPost, api.KindPage} {
+		posts, err := client.ListPosts(context.Background(), kind)
+		if err != nil {
+			return nil, err
+		}
+		into := dir
+		if kind == api.KindPage {
+			into = filepath.Join(dir, "pages")
+		}
+		for _, post := range posts {
+			if tracked[post.ID] {
+				continue
+			}
+			name := post.Slug
+			if name == "" {
+				name = post.ID
+			}
+			file := filepath.Join(into, name+".md")
+			r := result{File: file}
+			if _, err := os.Stat(file); err == nil {
+				r.Outcome, r.Err = outcomeFailed, fmt.Errorf("file exists but does not track %s %s", kind.Singular(), post.ID)
+			} else if err := os.MkdirAll(into, 0o755); err != nil {
+				r.Outcome, r.Err = outcomeFailed, err
+			} else {
+				r.Outcome, r.Err = writePulled(client, nil, file, frontmatter.Meta{}, nil, post)
+			}
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+func pullCmd() *cobra.Command {
+	var all bool
+	var out string
+	var concurrency int
+
+	cmd := &cobra.Command{
+		Use:   "pull [file|dir|glob]...",
+		Short: "Pull edits made in Ghost back into Markdown",
+		Long: `Overwrite published Markdown files with their current title, tags,
+excerpt, status, body and other fields from Ghost. Files without a
+post_id are skipped.
+
+With --all, every post in Ghost that isn't tracked by a file under --out
+is exported to a new <slug>.md file there; pages go to pages/<slug>.md.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := api.New(cfg.APIURL, cfg.AdminJWT)
+			imgs, err := newImages()
+			if err != nil {
+				return err
+			}
+
+			var results []result
+			if all {
+				if results, err = exportAll(client, out); err != nil {
+					return err
+				}
+			} else {
+				posts, err := findPosts(args)
+				if err != nil {
+					return err
+				}
+				if len(posts) == 0 {
+					return fmt.Errorf("no Markdown files given (or use --all)")
+				}
+				results = runBatch(posts, concurrency, func(file string) (outcome, error) {
+					return pullFile(client, imgs, file)
+				})
+			}
+
+			if failed := printSummary(results); failed > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d of %d file(s) failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Export every post and page in Ghost that no local file tracks yet")
+	cmd.Flags().StringVarP(&out, "out", "o", ".", "Directory for files created by --all")
+	cmd.Flags().IntVarP(&concurrency, "concurrency", "j", 4, "Number of posts to pull in parallel")
+	return cmd
+}
@@ -0,0 +1,73 @@
+// cmd/ghostpost/pull_test.go
+
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/api"
+)
+
+func TestPullBody(t *testing.T) {
+	const callout = `{"root":{"type":"root","children":[{"type":"callout","calloutEmoji":"💡","backgroundColor":"blue","calloutText":"Edited in Ghost."}]}}`
+	tests := []struct {
+		name, lexical, html, want string
+	}{
+		{"from Lexical", callout, `<div class="kg-card kg-callout-card">Edited in Ghost.</div>`, ":::callout{emoji=\"💡\" color=\"blue\"}\nEdited in Ghost.\n:::\n"},
+		{"Lexical without a Markdown form", `{"root":{"type":"root","children":[{"type":"embed","html":"<iframe></iframe>"}]}}`, "<p>Edited in Ghost.</p>", "Edited in Ghost.\n"},
+		{"no Lexical", "", "<p>Edited in <b>Ghost</b>.</p>", "Edited in **Ghost**.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, g := newTestPublisher(t)
+			id := published(t, p, "posts/a.md", "---\ntitle: A\n---\nFirst.\n")
+			g.Edit(id, func(p *api.Post) { p.Lexical, p.HTML = tt.lexical, tt.html })
+
+			if o, err := pullFile(p.client, p.images, "posts/a.md"); err != nil || o != outcomeUpdated {
+				t.Fatalf("pull = %v, %v, want updated", o, err)
+			}
+			raw, _ := os.ReadFile("posts/a.md")
+			if !strings.Contains(string(raw), "---\n\n"+tt.want) {
+				t.Errorf("file is\n%s\nwant the body\n%s", raw, tt.want)
+			}
+		})
+	}
+}
+
+func TestPullImageFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		ghost string // feature_image in Ghost after the edit; "-" for the uploaded one
+		want  string
+	}{
+		{"unchanged in Ghost", "-", "hero.png"},
+		{"replaced in Ghost", "https://e.com/other.png", "https://e.com/other.png"},
+		{"cleared in Ghost", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, g := newTestPublisher(t)
+			writePost(t, "posts/hero.png", string(testPNG(t)))
+			id := published(t, p, "posts/a.md", "---\ntitle: A\nfeature_image: hero.png\n---\nFirst.\n")
+			g.Edit(id, func(p *api.Post) {
+				p.HTML = "<p>Edited in Ghost.</p>"
+				if tt.ghost != "-" {
+					p.FeatureImage = tt.ghost
+				}
+			})
+
+			if _, err := pullFile(p.client, p.images, "posts/a.md"); err != nil {
+				t.Fatal(err)
+			}
+			meta, _, err := parseFile("posts/a.md")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.FeatureImage != tt.want {
+				t.Errorf("feature_image = %q, want %q", meta.FeatureImage, tt.want)
+			}
+		})
+	}
+}
@@ -3,9 +3,11 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/rodchristiansen/ghost-gitops-publishing/internal/config"
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/render"
 	"github.com/spf13/cobra"
 )
 
@@ -17,17 +19,33 @@ func main() {
 		Short: "Git-first publishing to Ghost",
 		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
 			var err error
-			cfg, err = config.Load(cmd)
-			return err
+			if cfg, err = config.Load(cmd); err != nil {
+				return err
+			}
+			if err := render.Extensions(cfg.Markdown).Check(); err != nil {
+				return fmt.Errorf("markdown: %w", err)
+			}
+			if err := render.Highlight(cfg.Highlight).Check(); err != nil {
+				return fmt.Errorf("highlight: %w", err)
+			}
+			return loadState()
 		},
 	}
 
 	root.PersistentFlags().String("api-url", "", "Ghost Admin API base URL (https://blog.example/ghost/api/admin/)")
 	root.PersistentFlags().String("admin-jwt", "", "Admin API JWT")
+	root.PersistentFlags().String("state-file", "", "Keep post IDs and hashes in this file instead of front-matter (e.g. .ghostpost/state.json)")
+	root.PersistentFlags().String("renderer", "", "Send posts as html (converted by Ghost) or lexical (Ghost's editor format)")
+	root.PersistentFlags().String("image-cache", ".ghostpost/images.json", "Remember uploaded images in this file across runs (\"\" to disable)")
 
 	root.AddCommand(publishCmd())
+	root.AddCommand(planCmd())
+	root.AddCommand(applyCmd())
+	root.AddCommand(pullCmd())
+	root.AddCommand(stateCmd())
 	root.AddCommand(tagsCmd())
 	root.AddCommand(imagesCmd())
+	root.AddCommand(cssCmd())
 
 	if err := root.Execute(); err != nil {
 		os.Exit(1)
@@ -0,0 +1,147 @@
+// cmd/ghostpost/state.go
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/frontmatter"
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/state"
+
+	"github.com/spf13/cobra"
+)
+
+// sidecar is the repository-level state file, or nil when state lives in
+// each post's front-matter.
+var sidecar *state.File
+
+func loadState() error {
+	if cfg.StateFile == "" {
+		return nil
+	}
+	var err error
+	sidecar, err = state.Load(cfg.StateFile)
+	return err
+}
+
+// parseFile is frontmatter.ParseFile with the ghostpost-managed keys taken
+// from the sidecar state file, if one is in use and knows the file.
+func parseFile(file string) (frontmatter.Meta, []byte, error) {
+	meta, md, err := frontmatter.ParseFile(file)
+	if err != nil || sidecar == nil {
+		return meta, md, err
+	}
+	if e, ok := sidecar.Get(file, meta.Slug); ok {
+		meta.PostID, meta.Hash = e.PostID, e.Hash
+		meta.RemoteUpdatedAt, meta.RemoteHash = e.RemoteUpdatedAt, e.RemoteHash
+	}
+	return meta, md, nil
+}
+
+// putState saves meta's managed keys, and the image URLs the post uses,
+// to the sidecar state file. Nil images keeps the ones already recorded.
+func putState(file string, meta frontmatter.Meta, images map[string]string) error {
+	if old, ok := sidecar.Get(file, meta.Slug); ok && images == nil {
+		images = old.Images
+	}
+	return sidecar.Put(file, state.Entry{
+		Slug:            meta.Slug,
+		PostID:          meta.PostID,
+		Hash:            meta.Hash,
+		RemoteUpdatedAt: meta.RemoteUpdatedAt,
+		RemoteHash:      meta.RemoteHash,
+		Images:          images,
+	})
+}
+
+// withoutState clears the keys that live in the sidecar state file.
+func withoutState(meta frontmatter.Meta) frontmatter.Meta {
+	meta.PostID, meta.Hash = "", ""
+	meta.RemoteUpdatedAt, meta.RemoteHash = "", ""
+	return meta
+}
+
+func stateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Manage where ghostpost keeps post IDs and hashes",
+	}
+	cmd.AddCommand(stateMigrateCmd())
+	return cmd
+}
+
+func stateMigrateCmd() *cobra.Command {
+	var to string
+
+	cmd := &cobra.Command{
+		Use:   "migrate [file|dir|glob]...",
+		Short: "Move post IDs and hashes between front-matter and the state file",
+		Long: `Move post_id, hash, remote_updated_at and remote_hash out of each
+file's front-matter into the state file (--to file), or back (--to
+frontmatter). The state file must be configured with state_file or
+--state-file either way.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if sidecar == nil {
+				return fmt.Errorf("no state file configured: set state_file (e.g. .ghostpost/state.json) or pass --state-file")
+			}
+			var migrate func(string) (outcome, error)
+			switch to {
+			case "file":
+				migrate = toStateFile
+			case "frontmatter":
+				migrate = toFrontmatter
+			default:
+				return fmt.Errorf("unknown --to %q (want file or frontmatter)", to)
+			}
+
+			posts, err := findPosts(args)
+			if err != nil {
+				return err
+			}
+			results := runBatch(posts, 1, migrate)
+			if failed := printSummary(results); failed > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d of %d file(s) failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&to, "to", "file", "Where state should live: file | frontmatter")
+	return cmd
+}
+
+func toStateFile(file string) (outcome, error) {
+	meta, md, err := frontmatter.ParseFile(file)
+	if err != nil {
+		return outcomeFailed, err
+	}
+	if meta.PostID == "" {
+		return outcomeSkipped, nil
+	}
+	if err := putState(file, meta, nil); err != nil {
+		return outcomeFailed, err
+	}
+	if err := frontmatter.WriteFile(file, withoutState(meta), md); err != nil {
+		return outcomeFailed, err
+	}
+	return outcomeUpdated, nil
+}
+
+func toFrontmatter(file string) (outcome, error) {
+	meta, md, err := parseFile(file)
+	if err != nil {
+		return outcomeFailed, err
+	}
+	if _, ok := sidecar.Get(file, meta.Slug); !ok {
+		return outcomeSkipped, nil
+	}
+	if err := frontmatter.WriteFile(file, meta, md); err != nil {
+		return outcomeFailed, err
+	}
+	if err := sidecar.Delete(file); err != nil {
+		return outcomeFailed, err
+	}
+	return outcomeUpdated, nil
+}
@@ -0,0 +1,63 @@
+// cmd/ghostpost/state_test.go
+
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/state"
+)
+
+func TestStateMigrate(t *testing.T) {
+	newTestPublisher(t)
+	var err error
+	if sidecar, err = state.Load(".ghostpost/state.json"); err != nil {
+		t.Fatal(err)
+	}
+	const managed = "post_id: p1\nhash: h1\nremote_updated_at: \"2026-01-01T00:00:00Z\"\nremote_hash: r1\n"
+	original := "---\ntitle: A # keep this comment\nslug: a\n" + managed + "---\nBody.\n"
+	writePost(t, "posts/a.md", original)
+	writePost(t, "posts/draft.md", "---\ntitle: Never published\n---\nBody.\n")
+
+	for _, file := range []string{"posts/a.md", "posts/draft.md"} {
+		if _, err := toStateFile(file); err != nil {
+			t.Fatalf("to file: %s: %v", file, err)
+		}
+	}
+	raw, _ := os.ReadFile("posts/a.md")
+	if strings.Contains(string(raw), "post_id") || strings.Contains(string(raw), "hash") {
+		t.Errorf("front-matter still has state after migrating to the file:\n%s", raw)
+	}
+	if !strings.Contains(string(raw), "# keep this comment") {
+		t.Errorf("comment lost:\n%s", raw)
+	}
+	e, ok := sidecar.Get("posts/a.md", "")
+	want := state.Entry{Slug: "a", PostID: "p1", Hash: "h1", RemoteUpdatedAt: "2026-01-01T00:00:00Z", RemoteHash: "r1"}
+	if !ok || !reflect.DeepEqual(e, want) {
+		t.Errorf("state = %+v, %v, want %+v", e, ok, want)
+	}
+	if _, ok := sidecar.Get("posts/draft.md", ""); ok {
+		t.Error("an unpublished post got a state entry")
+	}
+	meta, _, _ := parseFile("posts/a.md")
+	if meta.PostID != "p1" || meta.Hash != "h1" {
+		t.Errorf("parseFile = %s/%s, want the state file's p1/h1", meta.PostID, meta.Hash)
+	}
+
+	if o, err := toFrontmatter("posts/a.md"); err != nil || o != outcomeUpdated {
+		t.Fatalf("to front-matter = %v, %v", o, err)
+	}
+	if o, err := toFrontmatter("posts/draft.md"); err != nil || o != outcomeSkipped {
+		t.Errorf("to front-matter, unpublished = %v, %v, want skipped", o, err)
+	}
+	raw, _ = os.ReadFile("posts/a.md")
+	if string(raw) != original {
+		t.Errorf("round trip changed the file:\n got %q\nwant %q", raw, original)
+	}
+	if _, ok := sidecar.Get("posts/a.md", ""); ok {
+		t.Error("state entry left behind after moving it to front-matter")
+	}
+}
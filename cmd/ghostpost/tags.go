@@ -2,14 +2,368 @@
 
 package main
 
-import "github.com/spf13/cobra"
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/api"
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/tags"
+
+	"github.com/spf13/cobra"
+)
+
+// tagsFlags are the flags every tags subcommand shares.
+type tagsFlags struct {
+	file  string   // tags.yaml
+	posts []string // what tag usage is checked against
+}
 
 func tagsCmd() *cobra.Command {
-	return &cobra.Command{
+	f := &tagsFlags{}
+	cmd := &cobra.Command{
 		Use:   "tags",
-		Short: "Tag operations (placeholder)",
-		Run: func(_ *cobra.Command, _ []string) {
-			println("Not implemented yet—coming soon.")
+		Short: "Manage Ghost tags as code in tags.yaml",
+		Long: `Keep tag names, slugs, descriptions, accent colours, feature images,
+meta fields and visibility in a tags.yaml file and sync them with Ghost.
+
+Tags that exist in Ghost but aren't used by any post under --posts are
+reported by list and push, and can be removed with delete --unused --yes.`,
+	}
+	cmd.PersistentFlags().StringVar(&f.file, "file", "tags.yaml", "Tags file")
+	cmd.PersistentFlags().StringSliceVar(&f.posts, "posts", []string{"."}, "Posts to check tag usage against (files, dirs or globs)")
+
+	cmd.AddCommand(tagsListCmd(f))
+	cmd.AddCommand(tagsPullCmd(f))
+	cmd.AddCommand(tagsPushCmd(f))
+	cmd.AddCommand(tagsDeleteCmd(f))
+	return cmd
+}
+
+// usedTags collects the tag names every post under posts uses.
+func usedTags(posts []string) (map[string]bool, error) {
+	files, err := findPosts(posts)
+	if err != nil {
+		return nil, err
+	}
+	used := map[string]bool{}
+	for _, f := range files {
+		meta, _, err := parseFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		tags.Names(meta.Tags, used)
+	}
+	return used, nil
+}
+
+// unusedTags are the tags no local post uses (see usedTags) and no post
+// in Ghost has either, so deleting them loses nothing.
+func unusedTags(remote []api.Tag, used map[string]bool) []api.Tag {
+	var out []api.Tag
+	for _, r := range remote {
+		if !tags.Used(r, used) && (r.Count == nil || r.Count.Posts == 0) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func reportUnused(unused []api.Tag) {
+	if len(unused) == 0 {
+		return
+	}
+	names := make([]string, len(unused))
+	for i, t := range unused {
+		names[i] = t.Name
+	}
+	fmt.Printf("\n%d tag(s) in Ghost are not used by any post, here or in Ghost: %s\n", len(unused), strings.Join(names, ", "))
+	fmt.Println("Remove them with `ghostpost tags delete --unused --yes`.")
+}
+
+func findTag(local tags.Tag, remote []api.Tag) (api.Tag, bool) {
+	for _, r := range remote {
+		if local.Matches(r) {
+			return r, true
+		}
+	}
+	return api.Tag{}, false
+}
+
+func tagsListCmd(f *tagsFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List Ghost tags and how they compare with tags.yaml",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client := api.New(cfg.APIURL, cfg.AdminJWT)
+			remote, err := client.ListTags(context.Background())
+			if err != nil {
+				return err
+			}
+			local, err := tags.Load(f.file)
+			if err != nil {
+				return err
+			}
+			used, err := usedTags(f.posts)
+			if err != nil {
+				return err
+			}
+			sort.Slice(remote, func(i, j int) bool { return remote[i].Name < remote[j].Name })
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tSLUG\tPOSTS\tNOTES")
+			for _, r := range remote {
+				var notes []string
+				inFile := false
+				for _, l := range local {
+					if l.Matches(r) {
+						inFile = true
+						if !l.Equal(r) {
+							notes = append(notes, "differs from "+f.file)
+						}
+					}
+				}
+				if !inFile {
+					notes = append(notes, "not in "+f.file)
+				}
+				if !tags.Used(r, used) {
+					notes = append(notes, "unused here")
+				}
+				posts := "-"
+				if r.Count != nil {
+					posts = fmt.Sprint(r.Count.Posts)
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Name, r.Slug, posts, strings.Join(notes, ", "))
+			}
+			for _, l := range local {
+				if _, ok := findTag(l, remote); !ok {
+					fmt.Fprintf(tw, "%s\t%s\t-\tnot in Ghost\n", l.Name, l.Slug)
+				}
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			reportUnused(unusedTags(remote, used))
+			return nil
+		},
+	}
+}
+
+func tagsPullCmd(f *tagsFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "pull",
+		Short: "Write Ghost's tags to tags.yaml",
+		Long: `Overwrite tags.yaml with every tag in Ghost. Tags already in the file
+keep their place; new ones are added at the end.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client := api.New(cfg.APIURL, cfg.AdminJWT)
+			remote, err := client.ListTags(context.Background())
+			if err != nil {
+				return err
+			}
+			local, err := tags.Load(f.file)
+			if err != nil {
+				return err
+			}
+			sort.Slice(remote, func(i, j int) bool { return remote[i].Name < remote[j].Name })
+
+			var out []tags.Tag
+			taken := map[string]bool{}
+			for _, l := range local {
+				r, ok := findTag(l, remote)
+				if !ok {
+					fmt.Printf("- removed  %s\n", l.Name)
+					continue
+				}
+				if !l.Equal(r) {
+					fmt.Printf("✓ updated  %s\n", r.Name)
+				}
+				out = append(out, tags.FromAPI(r))
+				taken[r.ID] = true
+			}
+			for _, r := range remote {
+				if !taken[r.ID] {
+					fmt.Printf("✓ added    %s\n", r.Name)
+					out = append(out, tags.FromAPI(r))
+				}
+			}
+			if err := tags.Save(f.file, out); err != nil {
+				return err
+			}
+			fmt.Printf("\nWrote %d tag(s) to %s\n", len(out), f.file)
+			return nil
+		},
+	}
+}
+
+func tagsPushCmd(f *tagsFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "push",
+		Short: "Create and update Ghost tags from tags.yaml",
+		Long: `Create every tag in tags.yaml that Ghost doesn't have and update the
+ones that differ. Tags missing from the file are left alone; see delete.
+A local feature_image path is uploaded, relative to tags.yaml.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := context.Background()
+			client := api.New(cfg.APIURL, cfg.AdminJWT)
+			imgs, err := newImages()
+			if err != nil {
+				return err
+			}
+			local, err := tags.Load(f.file)
+			if err != nil {
+				return err
+			}
+			remote, err := client.ListTags(ctx)
+			if err != nil {
+				return err
+			}
+			used, err := usedTags(f.posts)
+			if err != nil {
+				return err
+			}
+
+			counts := map[outcome]int{}
+			for _, l := range local {
+				if path := imgs.Source(l.FeatureImage, filepath.Dir(f.file)); path != "" {
+					if l.FeatureImage, err = imgs.Upload(path); err != nil {
+						fmt.Printf("✗ %-8s %s: feature_image: %v\n", outcomeFailed, l.Name, err)
+						counts[outcomeFailed]++
+						continue
+					}
+				}
+				t := l.API()
+				o := outcomeCreated
+				if r, ok := findTag(l, remote); ok {
+					if l.Equal(r) {
+						counts[outcomeSkipped]++
+						continue
+					}
+					t.ID, o = r.ID, outcomeUpdated
+				}
+				if _, err := client.SaveTag(ctx, t); err != nil {
+					fmt.Printf("✗ %-8s %s: %v\n", outcomeFailed, l.Name, err)
+					counts[outcomeFailed]++
+					continue
+				}
+				fmt.Printf("✓ %-8s %s\n", o, l.Name)
+				counts[o]++
+			}
+			fmt.Printf("\n%d tag(s): %d created, %d updated, %d unchanged, %d failed\n",
+				len(local), counts[outcomeCreated], counts[outcomeUpdated],
+				counts[outcomeSkipped], counts[outcomeFailed])
+			reportUnused(unusedTags(remote, used))
+
+			if n := counts[outcomeFailed]; n > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d of %d tag(s) failed", n, len(local))
+			}
+			return nil
+		},
+	}
+}
+
+func tagsDeleteCmd(f *tagsFlags) *cobra.Command {
+	var unused, yes bool
+
+	cmd := &cobra.Command{
+		Use:   "delete [name|slug]...",
+		Short: "Delete tags from Ghost and tags.yaml",
+		Long: `Delete the named tags from Ghost, and from every post there that uses
+them. With --unused, list every tag that no post under --posts and no
+post in Ghost uses; add --yes to delete them.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !unused {
+				return fmt.Errorf("name the tags to delete, or pass --unused")
+			}
+			ctx := context.Background()
+			client := api.New(cfg.APIURL, cfg.AdminJWT)
+			remote, err := client.ListTags(ctx)
+			if err != nil {
+				return err
+			}
+
+			var doomed []api.Tag
+			for _, arg := range args {
+				r, ok := findTag(tags.Tag{Name: arg}, remote)
+				if !ok {
+					r, ok = findTag(tags.Tag{Slug: arg}, remote)
+				}
+				if !ok {
+					return fmt.Errorf("no tag %q in Ghost", arg)
+				}
+				doomed = append(doomed, r)
+			}
+			if unused {
+				used, err := usedTags(f.posts)
+				if err != nil {
+					return err
+				}
+				candidates := unusedTags(remote, used)
+				if !yes {
+					if len(candidates) == 0 {
+						fmt.Println("No unused tags.")
+					}
+					for _, r := range candidates {
+						fmt.Printf("- would delete %s\n", r.Name)
+					}
+					if len(candidates) > 0 {
+						fmt.Printf("\n%d unused tag(s). Run again with --yes to delete them.\n", len(candidates))
+					}
+					if len(args) == 0 {
+						return nil
+					}
+				} else {
+					doomed = append(doomed, candidates...)
+				}
+			}
+
+			local, err := tags.Load(f.file)
+			if err != nil {
+				return err
+			}
+			failed := 0
+			deleted := map[string]bool{}
+			for _, r := range doomed {
+				if deleted[r.ID] {
+					continue
+				}
+				if err := client.DeleteTag(ctx, r.ID); err != nil {
+					fmt.Printf("✗ %-8s %s: %v\n", outcomeFailed, r.Name, err)
+					failed++
+					continue
+				}
+				fmt.Printf("✓ deleted  %s\n", r.Name)
+				deleted[r.ID] = true
+			}
+
+			// keep tags.yaml from creating them again on the next push
+			var kept []tags.Tag
+			for _, l := range local {
+				if r, ok := findTag(l, remote); !ok || !deleted[r.ID] {
+					kept = append(kept, l)
+				}
+			}
+			if len(kept) != len(local) {
+				if err := tags.Save(f.file, kept); err != nil {
+					return err
+				}
+			}
+
+			fmt.Printf("\n%d tag(s) deleted, %d failed\n", len(deleted), failed)
+			if failed > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d tag(s) could not be deleted", failed)
+			}
+			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&unused, "unused", false, "List the Ghost tags no post, under --posts or in Ghost, uses")
+	cmd.Flags().BoolVar(&yes, "yes", false, "With --unused, delete the tags listed")
+	return cmd
 }
@@ -0,0 +1,45 @@
+// cmd/ghostpost/tags_test.go
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/api"
+)
+
+func TestUnusedTags(t *testing.T) {
+	newTestPublisher(t)
+	writePost(t, "posts/a.md", "---\ntitle: A\ntags: [Go, news]\n---\nA.\n")
+	writePost(t, "posts/b.md", "---\ntitle: B\ntags: [web]\n---\nB.\n")
+	writePost(t, "drafts/c.md", "---\ntitle: C\ntags: [Rust]\n---\nC.\n")
+
+	count := func(n int) *struct {
+		Posts int `json:"posts"`
+	} {
+		return &struct {
+			Posts int `json:"posts"`
+		}{n}
+	}
+	remote := []api.Tag{
+		{Name: "go", Slug: "go"},                      // used here, by name
+		{Name: "News", Slug: "news", Count: count(0)}, // used here, by slug
+		{Name: "Rust", Slug: "rust"},                  // used only outside --posts
+		{Name: "Old", Slug: "old", Count: count(3)},   // used in Ghost only
+		{Name: "Gone", Slug: "gone", Count: count(0)},
+		{Name: "Uncounted", Slug: "uncounted"},
+	}
+
+	used, err := usedTags([]string{"posts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range unusedTags(remote, used) {
+		got = append(got, r.Name)
+	}
+	if want := []string{"Rust", "Gone", "Uncounted"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unused = %v, want %v", got, want)
+	}
+}
@@ -8,19 +8,41 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"mime/multipart"
 	"net/http"
-	"path/filepath"
 	"strings"
 	"time"
 )
 
 type Client struct {
-	Base     string
-	JWT      string
-	hc       *http.Client
-	lastBody io.Reader // stores the last response body for debugging
+	Base string
+	JWT  string
+	hc   *http.Client
+}
+
+// APIError is a response Ghost refused, with the body it sent back.
+type APIError struct {
+	Status string
+	Body   []byte
+}
+
+func (e *APIError) Error() string {
+	var res struct {
+		Errors []struct {
+			Message string `json:"message"`
+			Context string `json:"context"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(e.Body, &res) == nil && len(res.Errors) > 0 {
+		msgs := make([]string, len(res.Errors))
+		for i, m := range res.Errors {
+			msgs[i] = m.Message
+			if m.Context != "" {
+				msgs[i] += " (" + m.Context + ")"
+			}
+		}
+		return fmt.Sprintf("ghost API error: %s: %s", e.Status, strings.Join(msgs, "; "))
+	}
+	return fmt.Sprintf("ghost API error: %s %s", e.Status, bytes.TrimSpace(e.Body))
 }
 
 func (c *Client) ListAuthors(ctx context.Context) ([]AuthorRef, error) {
@@ -65,42 +87,35 @@ func (c *Client) do(ctx context.Context, method, path string, body io.Reader, ct
 }
 
 func (c *Client) Get(ctx context.Context, path string, out any) error {
-	res, err := c.do(ctx, http.MethodGet, path, nil, "")
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
-
-	if !strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") {
-		body, _ := io.ReadAll(res.Body)
-		return fmt.Errorf("ghost API error: %s", bytes.TrimSpace(body))
-	}
-	return json.NewDecoder(res.Body).Decode(out)
+	return c.call(ctx, http.MethodGet, path, nil, out)
 }
 
 func (c *Client) Post(ctx context.Context, path string, payload any, out any) error {
-	buf, _ := json.Marshal(payload)
-	res, err := c.do(ctx, http.MethodPost, path, bytes.NewReader(buf), "application/json")
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
+	return c.call(ctx, http.MethodPost, path, payload, out)
+}
 
-	respBody, err := io.ReadAll(res.Body)
-	if err != nil {
-		return err
-	}
-	c.lastBody = bytes.NewReader(respBody)
+func (c *Client) Put(ctx context.Context, path string, payload any, out any) error {
+	return c.call(ctx, http.MethodPut, path, payload, out)
+}
 
-	if !strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") {
-		return fmt.Errorf("ghost API error: %s", bytes.TrimSpace(respBody))
-	}
-	return json.Unmarshal(respBody, out)
+func (c *Client) Delete(ctx context.Context, path string) error {
+	return c.call(ctx, http.MethodDelete, path, nil, nil)
 }
 
-func (c *Client) Put(ctx context.Context, path string, payload any, out any) error {
-	buf, _ := json.Marshal(payload)
-	res, err := c.do(ctx, http.MethodPut, path, bytes.NewReader(buf), "application/json")
+// call sends payload, if any, as JSON and decodes the JSON response into
+// out, if any. A response Ghost refused is an *APIError, so each caller
+// gets its own response body even when the client is shared.
+func (c *Client) call(ctx context.Context, method, path string, payload any, out any) error {
+	var body io.Reader
+	ctype := ""
+	if payload != nil {
+		buf, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body, ctype = bytes.NewReader(buf), "application/json"
+	}
+	res, err := c.do(ctx, method, path, body, ctype)
 	if err != nil {
 		return err
 	}
@@ -110,49 +125,37 @@ func (c *Client) Put(ctx context.Context, path string, payload any, out any) err
 	if err != nil {
 		return err
 	}
-	c.lastBody = bytes.NewReader(respBody)
-
+	if res.StatusCode >= 300 {
+		return &APIError{Status: res.Status, Body: respBody}
+	}
+	if out == nil {
+		return nil
+	}
 	if !strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") {
-		return fmt.Errorf("ghost API error: %s", bytes.TrimSpace(respBody))
+		return &APIError{Status: res.Status, Body: respBody}
 	}
 	return json.Unmarshal(respBody, out)
 }
 
-func (c *Client) UploadImage(ctx context.Context, absPath string) (string, error) {
-	f, err := ioutil.ReadFile(absPath)
-	if err != nil {
-		return "", err
-	}
-	var b bytes.Buffer
-	w := multipart.NewWriter(&b)
-	fw, _ := w.CreateFormFile("file", filepath.Base(absPath))
-	_, _ = fw.Write(f)
-	_ = w.Close()
-
-	var resp struct {
-		Images []struct {
-			URL string `json:"url"`
-		} `json:"images"`
-	}
-
-	if err := c.Post(ctx, "images/upload/", &b, &resp); err != nil {
-		return "", err
+// GetPost fetches a post or page, with its HTML and Lexical document,
+// from Ghost.
+func (c *Client) GetPost(ctx context.Context, kind Kind, id string) (Post, error) {
+	var res map[string][]Post
+	if err := c.Get(ctx, kind.Path()+"/"+id+"/?formats=html,lexical", &res); err != nil {
+		return Post{}, err
 	}
-	if len(resp.Images) == 0 {
-		return "", fmt.Errorf("no image returned")
+	if len(res[kind.Path()]) == 0 {
+		return Post{}, fmt.Errorf("%s %s not found", kind.Singular(), id)
 	}
-	return resp.Images[0].URL, nil
+	return res[kind.Path()][0], nil
 }
 
-func (c *Client) GetPost(ctx context.Context, id string) (Post, error) {
-	var res struct {
-		Posts []Post `json:"posts"`
-	}
-	if err := c.Get(ctx, "posts/"+id+"/", &res); err != nil {
-		return Post{}, err
-	}
-	if len(res.Posts) == 0 {
-		return Post{}, fmt.Errorf("post %s not found", id)
+// ListPosts fetches every post (or page), with its HTML and Lexical
+// document, from Ghost.
+func (c *Client) ListPosts(ctx context.Context, kind Kind) ([]Post, error) {
+	var res map[string][]Post
+	if err := c.Get(ctx, kind.Path()+"/?limit=all&formats=html,lexical", &res); err != nil {
+		return nil, err
 	}
-	return res.Posts[0], nil
+	return res[kind.Path()], nil
 }
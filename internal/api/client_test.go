@@ -0,0 +1,94 @@
+// internal/api/client_test.go
+
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientRefusedResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		ctype   string
+		body    string
+		wantErr string // "" for success
+	}{
+		{"ok", 200, "application/json", `{"posts":[{"id":"p1"}]}`, ""},
+		{"unauthorised", 401, "application/json", `{"errors":[{"message":"Authorization failed"}]}`, "401 Unauthorized: Authorization failed"},
+		{"not found", 404, "application/json", `{"errors":[{"message":"Post not found.","context":"id p1"}]}`, "Post not found. (id p1)"},
+		{"validation", 422, "application/json", `{"errors":[{"message":"Validation error"}]}`, "422 Unprocessable Entity: Validation error"},
+		{"not JSON", 200, "text/html", `<html>maintenance</html>`, "<html>maintenance</html>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Ghost jwt" {
+					t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+				}
+				w.Header().Set("Content-Type", tt.ctype)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			c := New(srv.URL+"/", "jwt")
+
+			post, err := c.GetPost(context.Background(), KindPost, "p1")
+			if tt.wantErr == "" {
+				if err != nil || post.ID != "p1" {
+					t.Fatalf("GetPost = %+v, %v", post, err)
+				}
+				return
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("GetPost error = %v, want an *APIError", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("GetPost error = %q, want %q", err, tt.wantErr)
+			}
+			if err := c.Post(context.Background(), "posts/", map[string]string{}, &struct{}{}); !errors.As(err, &apiErr) {
+				t.Errorf("Post error = %v, want an *APIError", err)
+			}
+		})
+	}
+}
+
+func TestClientDelete(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"no content", 204, false},
+		{"not found", 404, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete {
+					t.Errorf("method = %s", r.Method)
+				}
+				if tt.status >= 300 {
+					w.Header().Set("Content-Type", "application/json")
+				}
+				w.WriteHeader(tt.status)
+				if tt.status >= 300 {
+					w.Write([]byte(`{"errors":[{"message":"Tag not found."}]}`))
+				}
+			}))
+			defer srv.Close()
+
+			err := New(srv.URL+"/", "jwt").DeleteTag(context.Background(), "t1")
+			var apiErr *APIError
+			if tt.wantErr != errors.As(err, &apiErr) {
+				t.Errorf("DeleteTag error = %v, want an *APIError: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,8 +2,28 @@
 
 package api
 
-type postReq struct {
-	Posts []Post `json:"posts"`
+import "strings"
+
+// Kind is the Ghost resource a post lives under: posts or pages. Both
+// take the same fields and the same create/update semantics.
+type Kind string
+
+const (
+	KindPost Kind = "posts"
+	KindPage Kind = "pages"
+)
+
+// Path is the endpoint for k; the zero Kind means posts.
+func (k Kind) Path() string {
+	if k == "" {
+		return string(KindPost)
+	}
+	return string(k)
+}
+
+// Singular is "post" or "page", as used in the editor URL and messages.
+func (k Kind) Singular() string {
+	return strings.TrimSuffix(k.Path(), "s")
 }
 
 type Post struct {
@@ -11,9 +31,12 @@ type Post struct {
 	Title          string      `json:"title"`
 	Slug           string      `json:"slug,omitempty"`
 	Status         string      `json:"status,omitempty"`
-	HTML           string      `json:"html"`
+	HTML           string      `json:"html,omitempty"`
+	Lexical        string      `json:"lexical,omitempty"`
 	FeatureImage   string      `json:"feature_image,omitempty"`
-	Tags           []tagRef    `json:"tags,omitempty"`
+	OGImage        string      `json:"og_image,omitempty"`
+	TwitterImage   string      `json:"twitter_image,omitempty"`
+	Tags           []TagRef    `json:"tags,omitempty"`
 	CustomExcerpt  string      `json:"custom_excerpt,omitempty"`
 	PublishedAt    string      `json:"published_at,omitempty"`
 	Visibility     string      `json:"visibility,omitempty"`
@@ -22,9 +45,10 @@ type Post struct {
 	Authors        []AuthorRef `json:"authors,omitempty"`
 	CustomTemplate string      `json:"custom_template,omitempty"`
 	UpdatedAt      string      `json:"updated_at,omitempty"`
+	URL            string      `json:"url,omitempty"` // read-only
 }
 
-type tagRef struct {
+type TagRef struct {
 	Name string `json:"name"`
 	Slug string `json:"slug,omitempty"`
 }
@@ -40,10 +64,10 @@ type TierRef struct {
 	Slug string `json:"slug,omitempty"`
 }
 
-func WrapTags(tags []string) []tagRef {
-	out := make([]tagRef, len(tags))
+func WrapTags(tags []string) []TagRef {
+	out := make([]TagRef, len(tags))
 	for i, t := range tags {
-		out[i] = tagRef{Name: t}
+		out[i] = TagRef{Name: t}
 	}
 	return out
 }
@@ -0,0 +1,77 @@
+// internal/api/tags.go
+
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+// Tag is a Ghost tag as the Admin API returns it.
+type Tag struct {
+	ID              string `json:"id,omitempty"`
+	Name            string `json:"name"`
+	Slug            string `json:"slug,omitempty"`
+	Description     string `json:"description,omitempty"`
+	FeatureImage    string `json:"feature_image,omitempty"`
+	Visibility      string `json:"visibility,omitempty"` // public | internal
+	MetaTitle       string `json:"meta_title,omitempty"`
+	MetaDescription string `json:"meta_description,omitempty"`
+	AccentColor     string `json:"accent_color,omitempty"`
+	Count           *struct {
+		Posts int `json:"posts"`
+	} `json:"count,omitempty"`
+}
+
+// ListTags fetches every tag, with post counts, from Ghost.
+func (c *Client) ListTags(ctx context.Context) ([]Tag, error) {
+	var res struct {
+		Tags []Tag `json:"tags"`
+	}
+	if err := c.Get(ctx, "tags/?limit=all&include=count.posts", &res); err != nil {
+		return nil, err
+	}
+	return res.Tags, nil
+}
+
+// SaveTag creates t, or updates it if it has an ID. On update, empty
+// fields are cleared in Ghost rather than left alone.
+func (c *Client) SaveTag(ctx context.Context, t Tag) (Tag, error) {
+	var res struct {
+		Tags []Tag `json:"tags"`
+	}
+	var err error
+	if t.ID == "" {
+		t.Count = nil
+		err = c.Post(ctx, "tags/", map[string][]Tag{"tags": {t}}, &res)
+	} else {
+		fields := map[string]any{
+			"name":             t.Name,
+			"slug":             t.Slug,
+			"description":      nullable(t.Description),
+			"feature_image":    nullable(t.FeatureImage),
+			"meta_title":       nullable(t.MetaTitle),
+			"meta_description": nullable(t.MetaDescription),
+			"accent_color":     nullable(t.AccentColor),
+		}
+		if t.Slug == "" {
+			delete(fields, "slug")
+		}
+		if t.Visibility != "" {
+			fields["visibility"] = t.Visibility
+		}
+		err = c.Put(ctx, "tags/"+t.ID+"/", map[string]any{"tags": []any{fields}}, &res)
+	}
+	if err != nil {
+		return Tag{}, err
+	}
+	if len(res.Tags) == 0 {
+		return Tag{}, fmt.Errorf("ghost API returned no tag for %q", t.Name)
+	}
+	return res.Tags[0], nil
+}
+
+// DeleteTag removes a tag from Ghost, and from every post that uses it.
+func (c *Client) DeleteTag(ctx context.Context, id string) error {
+	return c.Delete(ctx, "tags/"+id+"/")
+}
@@ -4,49 +4,147 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 )
 
+// ConflictError is returned by UpsertWith when the post was edited
+// in Ghost after the version the caller last saw.
+type ConflictError struct {
+	Kind     Kind
+	ID       string
+	LastSeen string // updated_at the caller last saw
+	Current  Post   // the post as it is in Ghost now
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s %s changed in Ghost since %s (now %s)", e.Kind.Singular(), e.ID, e.LastSeen, e.Current.UpdatedAt)
+}
+
+// UpsertOptions tunes how UpsertWith updates an existing post.
+type UpsertOptions struct {
+	// Kind is the endpoint to use: posts (the default) or pages.
+	Kind Kind
+	// LastSeen is the updated_at the caller last saw. If Ghost has moved on
+	// since, the update is refused with a *ConflictError. Empty skips the check.
+	LastSeen string
+	// GhostOwned lists fields (by JSON key, e.g. "tags") that are never sent
+	// on update, so edits made in Ghost are kept.
+	GhostOwned []string
+}
+
 func Upsert(c *Client, post Post, id string) (string, error) {
+	return UpsertWith(c, post, id, UpsertOptions{})
+}
+
+// UpsertWith is Upsert with options. On update, every field is reconciled
+// with the live post: empty values clear the field in Ghost instead of being
+// left out.
+func UpsertWith(c *Client, post Post, id string, opts UpsertOptions) (string, error) {
 	ctx := context.Background()
-	var res struct {
-		Posts []struct {
-			ID string `json:"id"`
-		}
+	kind := opts.Kind.Path()
+	var res map[string][]struct {
+		ID string `json:"id"`
+	}
+
+	// Ghost converts HTML into Lexical only when asked to; a Lexical
+	// document is stored as it is.
+	source := "?source=html"
+	if post.Lexical != "" {
+		source = ""
+		post.HTML = ""
 	}
 
 	if id == "" { // create
-		if err := c.Post(ctx, "posts/?source=html", postReq{Posts: []Post{post}}, &res); err != nil {
+		if err := c.Post(ctx, kind+"/"+source, map[string][]Post{kind: {post}}, &res); err != nil {
 			return "", err
 		}
 	} else { // update
 		// 1. fetch timestamp
-		current, err := c.GetPost(ctx, id)
+		current, err := c.GetPost(ctx, opts.Kind, id)
 		if err != nil {
 			return "", err
 		}
+		if opts.LastSeen != "" && current.UpdatedAt != opts.LastSeen {
+			return "", &ConflictError{Kind: opts.Kind, ID: id, LastSeen: opts.LastSeen, Current: current}
+		}
 		post.ID = id
 		post.UpdatedAt = current.UpdatedAt // required lock
-		post.Tags = nil                    // leave unchanged
-		post.FeatureImage = ""             // leave unchanged
 
-		if err := c.Put(ctx, "posts/"+id+"/?source=html", postReq{Posts: []Post{post}}, &res); err != nil {
+		fields := UpdateFields(post, opts.GhostOwned)
+		if err := c.Put(ctx, kind+"/"+id+"/"+source, map[string]any{kind: []any{fields}}, &res); err != nil {
 			return "", err
 		}
 	}
 
-	if len(res.Posts) == 0 {
-		// read full body again for debug
-		if data, _ := io.ReadAll(c.lastBody); len(data) > 0 {
-			var e map[string]any
-			if json.Unmarshal(data, &e) == nil {
-				return "", fmt.Errorf("ghost API error: %v", e)
-			}
-			return "", fmt.Errorf("ghost API raw: %s", data)
+	if len(res[kind]) == 0 {
+		return "", fmt.Errorf("ghost API returned empty %s array", kind)
+	}
+	return res[kind][0].ID, nil
+}
+
+// UpdateFields turns post into the body of an update request. Unlike the
+// create payload, cleared values are sent explicitly (null, [] or false) so
+// that removing a key from front-matter removes it in Ghost. Fields Ghost
+// can't do without (slug, published_at, authors) and tiers, which only mean
+// something for tier-restricted posts, are left alone when empty, as are the
+// ghostOwned ones. A post with a Lexical document sends it instead of the
+// HTML.
+func UpdateFields(post Post, ghostOwned []string) map[string]any {
+	fields := map[string]any{
+		"id":              post.ID,
+		"updated_at":      post.UpdatedAt,
+		"title":           post.Title,
+		"html":            post.HTML,
+		"status":          post.Status,
+		"feature_image":   nullable(post.FeatureImage),
+		"og_image":        nullable(post.OGImage),
+		"twitter_image":   nullable(post.TwitterImage),
+		"tags":            nonNil(post.Tags),
+		"custom_excerpt":  nullable(post.CustomExcerpt),
+		"visibility":      post.Visibility,
+		"featured":        post.Featured,
+		"custom_template": nullable(post.CustomTemplate),
+	}
+	if post.Visibility == "" {
+		fields["visibility"] = "public"
+	}
+	if post.Slug != "" {
+		fields["slug"] = post.Slug
+	}
+	if post.PublishedAt != "" {
+		fields["published_at"] = post.PublishedAt
+	}
+	if len(post.Authors) > 0 {
+		fields["authors"] = post.Authors
+	}
+	if len(post.Tiers) > 0 {
+		fields["tiers"] = post.Tiers
+	}
+	if post.Lexical != "" {
+		delete(fields, "html")
+		fields["lexical"] = post.Lexical
+	}
+	for _, k := range ghostOwned {
+		if k == "html" {
+			delete(fields, "lexical") // the body, in whichever form
 		}
-		return "", fmt.Errorf("ghost API returned empty posts array")
+		if k != "id" && k != "updated_at" {
+			delete(fields, k)
+		}
+	}
+	return fields
+}
+
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
 	}
-	return res.Posts[0].ID, nil
+	return s
 }
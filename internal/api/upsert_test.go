@@ -0,0 +1,193 @@
+// internal/api/upsert_test.go
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ghostStandIn serves one post, p1, updated at "t2", and records the
+// requests that change it.
+type ghostStandIn struct {
+	*httptest.Server
+	kind   Kind
+	writes []string         // "POST posts/?source=html", …
+	fields []map[string]any // the body of each write
+}
+
+func newGhostStandIn(t *testing.T, kind Kind) *ghostStandIn {
+	t.Helper()
+	g := &ghostStandIn{kind: kind}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		if r.URL.RawQuery != "" && r.Method != http.MethodGet {
+			path += "?" + r.URL.RawQuery
+		}
+		switch r.Method {
+		case http.MethodGet:
+			if path != kind.Path()+"/p1/" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"errors":[{"message":"Resource not found"}]}`))
+				return
+			}
+			w.Write([]byte(`{"` + kind.Path() + `":[{"id":"p1","title":"In Ghost","html":"<p>ghost</p>","updated_at":"t2"}]}`))
+		default:
+			var body map[string][]map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			g.writes = append(g.writes, r.Method+" "+path)
+			g.fields = append(g.fields, body[kind.Path()][0])
+			w.Write([]byte(`{"` + kind.Path() + `":[{"id":"p1"}]}`))
+		}
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+func TestUpsertWithConflicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		kind      Kind
+		id        string
+		lastSeen  string
+		wantWrite string // "" if nothing may be written
+	}{
+		{"create", KindPost, "", "", "POST posts/?source=html"},
+		{"create page", KindPage, "", "", "POST pages/?source=html"},
+		{"update, no check", KindPost, "p1", "", "PUT posts/p1/?source=html"},
+		{"update, unchanged in Ghost", KindPost, "p1", "t2", "PUT posts/p1/?source=html"},
+		{"update page, unchanged in Ghost", KindPage, "p1", "t2", "PUT pages/p1/?source=html"},
+		{"update, edited in Ghost", KindPost, "p1", "t1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newGhostStandIn(t, tt.kind)
+			c := New(g.URL+"/", "jwt")
+
+			id, err := UpsertWith(c, Post{Title: "Local", HTML: "<p>local</p>"}, tt.id, UpsertOptions{Kind: tt.kind, LastSeen: tt.lastSeen})
+			if tt.wantWrite == "" {
+				var conflict *ConflictError
+				if !errors.As(err, &conflict) {
+					t.Fatalf("err = %v, want a *ConflictError", err)
+				}
+				if conflict.LastSeen != tt.lastSeen || conflict.Current.UpdatedAt != "t2" || conflict.Current.Title != "In Ghost" {
+					t.Errorf("conflict = %+v, want last seen %s and Ghost's post", conflict, tt.lastSeen)
+				}
+				if len(g.writes) != 0 {
+					t.Errorf("wrote %v despite the conflict", g.writes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != "p1" {
+				t.Errorf("id = %q, want p1", id)
+			}
+			if len(g.writes) != 1 || g.writes[0] != tt.wantWrite {
+				t.Fatalf("writes = %v, want [%s]", g.writes, tt.wantWrite)
+			}
+			if tt.id != "" && g.fields[0]["updated_at"] != "t2" {
+				t.Errorf("update sent updated_at %v, want Ghost's t2 as the lock", g.fields[0]["updated_at"])
+			}
+		})
+	}
+}
+
+func TestUpsertWithLexical(t *testing.T) {
+	g := newGhostStandIn(t, KindPost)
+	c := New(g.URL+"/", "jwt")
+	post := Post{Title: "Local", HTML: "<p>local</p>", Lexical: `{"root":{}}`}
+	if _, err := UpsertWith(c, post, "p1", UpsertOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if g.writes[0] != "PUT posts/p1/" {
+		t.Errorf("write = %s, want no source=html with a Lexical document", g.writes[0])
+	}
+	if _, ok := g.fields[0]["html"]; ok || g.fields[0]["lexical"] != post.Lexical {
+		t.Errorf("fields = %v, want lexical and no html", g.fields[0])
+	}
+}
+
+func TestUpdateFields(t *testing.T) {
+	full := Post{
+		ID: "p1", UpdatedAt: "t2", Title: "T", HTML: "<p>x</p>", Status: "published",
+		Slug: "t", FeatureImage: "https://x/f.png", CustomExcerpt: "ex", Visibility: "members",
+		Tags: []TagRef{{Name: "go"}}, Authors: []AuthorRef{{ID: "a1"}}, Tiers: []TierRef{{ID: "t1"}},
+		Featured: true, PublishedAt: "2026-01-01T00:00:00Z",
+	}
+	tests := []struct {
+		name       string
+		post       Post
+		ghostOwned []string
+		want       map[string]any // fields that must have these values
+		absent     []string       // fields that must not be sent
+	}{
+		{
+			name: "everything set",
+			post: full,
+			want: map[string]any{
+				"feature_image": "https://x/f.png", "custom_excerpt": "ex", "visibility": "members",
+				"featured": true, "slug": "t", "published_at": "2026-01-01T00:00:00Z",
+			},
+		},
+		{
+			name: "cleared fields are sent empty",
+			post: Post{ID: "p1", UpdatedAt: "t2", Title: "T"},
+			want: map[string]any{
+				"feature_image": nil, "og_image": nil, "twitter_image": nil, "custom_excerpt": nil,
+				"custom_template": nil, "tags": []TagRef{}, "featured": false, "visibility": "public",
+			},
+		},
+		{
+			name:   "unset fields Ghost needs are left alone",
+			post:   Post{ID: "p1", UpdatedAt: "t2", Title: "T"},
+			absent: []string{"slug", "published_at", "authors", "tiers", "lexical"},
+		},
+		{
+			name:       "Ghost-owned fields are never sent",
+			post:       full,
+			ghostOwned: []string{"tags", "feature_image", "custom_excerpt", "id", "updated_at"},
+			want:       map[string]any{"id": "p1", "updated_at": "t2", "title": "T"},
+			absent:     []string{"tags", "feature_image", "custom_excerpt"},
+		},
+		{
+			name:       "a Ghost-owned body covers Lexical too",
+			post:       Post{ID: "p1", UpdatedAt: "t2", Title: "T", Lexical: `{"root":{}}`},
+			ghostOwned: []string{"html"},
+			absent:     []string{"html", "lexical"},
+		},
+		{
+			name:   "Lexical replaces HTML",
+			post:   Post{ID: "p1", UpdatedAt: "t2", Title: "T", HTML: "<p>x</p>", Lexical: `{"root":{}}`},
+			want:   map[string]any{"lexical": `{"root":{}}`},
+			absent: []string{"html"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UpdateFields(tt.post, tt.ghostOwned)
+			for k, want := range tt.want {
+				v, ok := got[k]
+				if !ok {
+					t.Errorf("%s not sent, want %#v", k, want)
+					continue
+				}
+				if !reflect.DeepEqual(v, want) {
+					t.Errorf("%s = %#v, want %#v", k, v, want)
+				}
+			}
+			for _, k := range tt.absent {
+				if v, ok := got[k]; ok {
+					t.Errorf("%s sent as %#v, want it left out", k, v)
+				}
+			}
+		})
+	}
+}
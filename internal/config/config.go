@@ -5,4 +5,73 @@ package config
 type Config struct {
 	APIURL   string
 	AdminJWT string
+
+	// GhostOwned lists front-matter keys that Ghost owns after the first
+	// publish: they are never sent on update.
+	GhostOwned []string
+
+	// StateFile, if set, holds post IDs and hashes instead of front-matter.
+	StateFile string
+
+	// ImageCache remembers uploaded images across runs, keyed by content.
+	// Empty disables it.
+	ImageCache string
+
+	// Renderer is how posts are sent to Ghost: html, converted to Lexical
+	// by Ghost, or lexical, built by ghostpost. Empty means html.
+	Renderer string
+
+	// Markdown switches Markdown extensions on or off by name (the
+	// markdown: section); posts can override it in their front-matter.
+	Markdown map[string]bool
+
+	// BrokenLinks is what to do about links to posts that are missing or
+	// not published: warn (the default) or fail.
+	BrokenLinks string
+
+	// Highlight configures syntax highlighting of fenced code (the
+	// highlight: section).
+	Highlight Highlight
+
+	// Images configures optimisation before upload (the images: section).
+	Images Images
+
+	// Uploads limits what may be uploaded (the uploads: section).
+	Uploads Uploads
+}
+
+// Uploads is the uploads: section of the config file. Sizes are in bytes
+// (the file may say "10MB"); 0 means no limit. Empty type lists mean
+// Ghost's defaults for images and media, and anything for files.
+type Uploads struct {
+	MaxImageSize int64
+	MaxMediaSize int64
+	MaxFileSize  int64
+	ImageTypes   []string
+	MediaTypes   []string
+	FileTypes    []string
+}
+
+// Highlight is the highlight: section of the config file. The zero value
+// leaves code blocks for Ghost to render.
+type Highlight struct {
+	Enabled     bool
+	Style       string // a chroma style; empty means github
+	Classes     bool   // CSS classes (see ghostpost css) instead of inline styles
+	LineNumbers bool
+}
+
+// Images is the images: section of the config file. The zero value
+// uploads images exactly as they are.
+type Images struct {
+	MaxWidth      int  // scale wider images down; 0 keeps the size
+	Quality       int  // JPEG quality 1-100; 0 keeps JPEGs as they are
+	WebP          bool // convert to WebP when that is smaller
+	StripMetadata bool // drop EXIF/GPS/XMP
+
+	// Mirror copies remote images into Ghost, limited to MirrorAllow
+	// domains (all if empty) and never MirrorDeny ones.
+	Mirror      bool
+	MirrorAllow []string
+	MirrorDeny  []string
 }
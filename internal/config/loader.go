@@ -3,6 +3,7 @@
 package config
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 
@@ -17,16 +18,65 @@ func Load(cmd *cobra.Command) (*Config, error) {
 	v.AddConfigPath("$HOME/.ghostpost")
 	v.AddConfigPath(".")
 	v.SetEnvPrefix("ghost")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_")) // images.max_width → GHOST_IMAGES_MAX_WIDTH
 	v.AutomaticEnv()
 
 	_ = v.BindPFlag("api_url", cmd.Flags().Lookup("api-url"))
 	_ = v.BindPFlag("admin_jwt", cmd.Flags().Lookup("admin-jwt"))
+	_ = v.BindPFlag("state_file", cmd.Flags().Lookup("state-file"))
+	_ = v.BindPFlag("image_cache", cmd.Flags().Lookup("image-cache"))
+	_ = v.BindPFlag("renderer", cmd.Flags().Lookup("renderer"))
+	v.SetDefault("image_cache", ".ghostpost/images.json")
 
 	_ = v.ReadInConfig() // ignore “file not found”
 
 	cfg := &Config{
-		APIURL:   v.GetString("api_url"),
-		AdminJWT: v.GetString("admin_jwt"),
+		APIURL:      v.GetString("api_url"),
+		AdminJWT:    v.GetString("admin_jwt"),
+		GhostOwned:  v.GetStringSlice("ghost_owned"),
+		StateFile:   v.GetString("state_file"),
+		ImageCache:  v.GetString("image_cache"),
+		Renderer:    v.GetString("renderer"),
+		Markdown:    map[string]bool{},
+		BrokenLinks: v.GetString("broken_links"),
+		Highlight: Highlight{
+			Enabled:     v.GetBool("highlight.enabled"),
+			Style:       v.GetString("highlight.style"),
+			Classes:     v.GetBool("highlight.classes"),
+			LineNumbers: v.GetBool("highlight.line_numbers"),
+		},
+		Images: Images{
+			MaxWidth:      v.GetInt("images.max_width"),
+			Quality:       v.GetInt("images.quality"),
+			WebP:          v.GetBool("images.webp"),
+			StripMetadata: v.GetBool("images.strip_metadata"),
+			Mirror:        v.GetBool("images.mirror"),
+			MirrorAllow:   v.GetStringSlice("images.mirror_allow"),
+			MirrorDeny:    v.GetStringSlice("images.mirror_deny"),
+		},
+		Uploads: Uploads{
+			MaxImageSize: int64(v.GetSizeInBytes("uploads.max_image_size")),
+			MaxMediaSize: int64(v.GetSizeInBytes("uploads.max_media_size")),
+			MaxFileSize:  int64(v.GetSizeInBytes("uploads.max_file_size")),
+			ImageTypes:   v.GetStringSlice("uploads.image_types"),
+			MediaTypes:   v.GetStringSlice("uploads.media_types"),
+			FileTypes:    v.GetStringSlice("uploads.file_types"),
+		},
+	}
+	for name := range v.GetStringMap("markdown") {
+		cfg.Markdown[name] = v.GetBool("markdown." + name)
+	}
+	if b := cfg.BrokenLinks; b != "" && b != "warn" && b != "fail" {
+		return nil, fmt.Errorf("broken_links must be warn or fail, not %q", b)
+	}
+	if r := cfg.Renderer; r != "" && r != "html" && r != "lexical" {
+		return nil, fmt.Errorf("renderer must be html or lexical, not %q", r)
+	}
+	if q := cfg.Images.Quality; q < 0 || q > 100 {
+		return nil, fmt.Errorf("images.quality must be between 1 and 100, not %d", q)
+	}
+	if cfg.Images.MaxWidth < 0 {
+		return nil, fmt.Errorf("images.max_width must not be negative")
 	}
 
 	// Accept raw Admin API key and auto-sign it.
@@ -7,26 +7,50 @@ import (
 	"os"
 
 	fm "github.com/adrg/frontmatter"
-	"gopkg.in/yaml.v3"
 )
 
 // Meta holds every key ghostpost cares about.
-// Add more tags as your workflow grows.
+// Add more tags as your workflow grows; keep the yaml, toml and json names
+// in step so every front-matter format adrg/frontmatter reads works.
 type Meta struct {
-	Title          string   `yaml:"title"`
-	Slug           string   `yaml:"slug,omitempty"`
-	Status         string   `yaml:"status,omitempty"` // draft | published | scheduled
-	PublishedAt    string   `yaml:"published_at,omitempty"`
-	Visibility     string   `yaml:"visibility,omitempty"` // public | members | paid | specific
-	Tiers          []string `yaml:"tiers,omitempty"`
-	Featured       bool     `yaml:"featured,omitempty"`
-	CustomExcerpt  string   `yaml:"custom_excerpt,omitempty"`
-	Authors        []string `yaml:"authors,omitempty"`
-	CustomTemplate string   `yaml:"custom_template,omitempty"`
-	FeatureImage   string   `yaml:"feature_image,omitempty"`
-	Tags           []string `yaml:"tags,omitempty"`
-	PostID         string   `yaml:"post_id,omitempty"` // set after first publish
-	Hash           string   `yaml:"hash,omitempty"`    // SHA256 of Markdown body
+	Title          string          `yaml:"title" toml:"title" json:"title"`
+	Slug           string          `yaml:"slug,omitempty" toml:"slug" json:"slug,omitempty"`
+	Type           string          `yaml:"type,omitempty" toml:"type" json:"type,omitempty"`       // post (default) | page
+	Status         string          `yaml:"status,omitempty" toml:"status" json:"status,omitempty"` // draft | published | scheduled
+	PublishedAt    string          `yaml:"published_at,omitempty" toml:"published_at" json:"published_at,omitempty"`
+	Visibility     string          `yaml:"visibility,omitempty" toml:"visibility" json:"visibility,omitempty"` // public | members | paid | specific
+	Tiers          []string        `yaml:"tiers,omitempty" toml:"tiers" json:"tiers,omitempty"`
+	Featured       bool            `yaml:"featured,omitempty" toml:"featured" json:"featured,omitempty"`
+	CustomExcerpt  string          `yaml:"custom_excerpt,omitempty" toml:"custom_excerpt" json:"custom_excerpt,omitempty"`
+	Authors        []string        `yaml:"authors,omitempty" toml:"authors" json:"authors,omitempty"`
+	CustomTemplate string          `yaml:"custom_template,omitempty" toml:"custom_template" json:"custom_template,omitempty"`
+	FeatureImage   string          `yaml:"feature_image,omitempty" toml:"feature_image" json:"feature_image,omitempty"`
+	OGImage        string          `yaml:"og_image,omitempty" toml:"og_image" json:"og_image,omitempty"`
+	TwitterImage   string          `yaml:"twitter_image,omitempty" toml:"twitter_image" json:"twitter_image,omitempty"`
+	Tags           []string        `yaml:"tags,omitempty" toml:"tags" json:"tags,omitempty"`
+	Markdown       map[string]bool `yaml:"markdown,omitempty" toml:"markdown" json:"markdown,omitempty"` // extensions on or off, over the site's
+	PostID         string          `yaml:"post_id,omitempty" toml:"post_id" json:"post_id,omitempty"`    // set after first publish
+	Hash           string          `yaml:"hash,omitempty" toml:"hash" json:"hash,omitempty"`             // content fingerprint, see publish
+
+	// What Ghost looked like after our last publish, for conflict detection
+	RemoteUpdatedAt string `yaml:"remote_updated_at,omitempty" toml:"remote_updated_at" json:"remote_updated_at,omitempty"`
+	RemoteHash      string `yaml:"remote_hash,omitempty" toml:"remote_hash" json:"remote_hash,omitempty"` // SHA256 of Ghost's HTML
+}
+
+// ImageField is a front-matter key that holds an image: a URL, or a local
+// path to upload.
+type ImageField struct {
+	Key   string
+	Value *string
+}
+
+// ImageFields lists m's image-valued keys.
+func (m *Meta) ImageFields() []ImageField {
+	return []ImageField{
+		{"feature_image", &m.FeatureImage},
+		{"og_image", &m.OGImage},
+		{"twitter_image", &m.TwitterImage},
+	}
 }
 
 // ParseFile reads a Markdown file and returns its meta + body bytes.
@@ -39,21 +63,3 @@ func ParseFile(path string) (Meta, []byte, error) {
 	body, err := fm.Parse(bytes.NewReader(raw), &meta)
 	return meta, body, err
 }
-
-// WriteFile rewrites the Markdown file with updated front-matter.
-func WriteFile(path string, meta Meta, body []byte) error {
-	// marshal the front-matter
-	fmBytes, _ := yaml.Marshal(meta)
-
-	// strip out any leading "\n" so we only get one blank line
-	body = bytes.TrimLeft(body, "\n")
-
-	// build the new file
-	var buf bytes.Buffer
-	buf.WriteString("---\n")
-	buf.Write(fmBytes)
-	buf.WriteString("---\n\n") // one blank line before the content
-	buf.Write(body)
-
-	return os.WriteFile(path, buf.Bytes(), 0o644)
-}
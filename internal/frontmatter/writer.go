@@ -0,0 +1,607 @@
+// internal/frontmatter/writer.go
+
+package frontmatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	fm "github.com/adrg/frontmatter"
+	"gopkg.in/yaml.v3"
+)
+
+// WriteFile rewrites the Markdown file with updated front-matter.
+//
+// Only keys whose values differ from what the file already says are
+// touched. Everything else in the front-matter (comments, key order,
+// quoting, list style and keys ghostpost doesn't know about) is kept as it
+// was. YAML (---), TOML (+++) and JSON front-matter are supported; a file
+// without any gets a new YAML block.
+func WriteFile(path string, meta Meta, body []byte) error {
+	raw, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	b := split(raw)
+	var old Meta
+	var oldBody []byte
+	if b.format != "" {
+		if oldBody, err = fm.Parse(bytes.NewReader(raw), &old); err != nil {
+			return err
+		}
+	}
+	edits := changedKeys(old, meta)
+
+	var data []byte
+	switch b.format {
+	case "toml":
+		data, err = editTOML(b.data, edits)
+	case "json":
+		data, err = editJSON(b.data, edits)
+	case "yaml":
+		data, err = editYAML(b.data, edits)
+	default:
+		b.open, b.close = []byte("---\n"), []byte("---\n")
+		data, err = editYAML(nil, edits)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	// A body we didn't change keeps its spacing; a new one gets exactly one
+	// blank line before the content.
+	if b.format == "" || !bytes.Equal(body, oldBody) {
+		body = append([]byte("\n"), bytes.TrimLeft(body, "\n")...)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(b.open)
+	buf.Write(data)
+	buf.Write(b.close)
+	buf.Write(body)
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// block is a file's front-matter, split the way adrg/frontmatter finds it.
+type block struct {
+	format string // yaml | toml | json, or "" if the file has none
+	open   []byte // everything up to the data, opening delimiter included
+	data   []byte
+	close  []byte // closing delimiter line
+}
+
+type delims struct {
+	format, start, end string
+	inData             bool // the delimiters are part of the data, as with bare JSON
+}
+
+var formats = []delims{
+	{"yaml", "---", "---", false},
+	{"yaml", "---yaml", "---", false},
+	{"toml", "+++", "+++", false},
+	{"toml", "---toml", "---", false},
+	{"json", ";;;", ";;;", false},
+	{"json", "---json", "---", false},
+	{"json", "{", "}", true},
+}
+
+func split(raw []byte) block {
+	lines := lines(raw)
+	i := 0
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	if i == len(lines) {
+		return block{}
+	}
+	for _, d := range formats {
+		if strings.TrimSpace(lines[i]) != d.start {
+			continue
+		}
+		for j := i + 1; j < len(lines); j++ {
+			if strings.TrimSpace(lines[j]) != d.end {
+				continue
+			}
+			if !d.inData {
+				return block{
+					format: d.format,
+					open:   []byte(strings.Join(lines[:i+1], "")),
+					data:   []byte(strings.Join(lines[i+1:j], "")),
+					close:  []byte(lines[j]),
+				}
+			}
+			// bare JSON must be followed by an empty line
+			if j+1 == len(lines) || strings.TrimSpace(lines[j+1]) == "" {
+				b := block{
+					format: d.format,
+					open:   []byte(strings.Join(lines[:i], "")),
+					data:   []byte(strings.Join(lines[i:j+1], "")),
+				}
+				if j+1 < len(lines) {
+					b.close = []byte(lines[j+1])
+				}
+				return b
+			}
+		}
+		return block{}
+	}
+	return block{}
+}
+
+// lines splits s after every newline, keeping the newlines.
+func lines(s []byte) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return strings.SplitAfter(string(s), "\n")
+}
+
+// edit sets key to value, or removes the key if value is nil.
+type edit struct {
+	key   string
+	value any
+}
+
+// changedKeys lists the Meta fields that differ between old and new, in
+// struct order. Empty values remove the key.
+func changedKeys(old, new Meta) []edit {
+	ov, nv := reflect.ValueOf(old), reflect.ValueOf(new)
+	t := ov.Type()
+	var edits []edit
+	for i := 0; i < t.NumField(); i++ {
+		o, n := ov.Field(i), nv.Field(i)
+		if isEmpty(o) && isEmpty(n) || reflect.DeepEqual(o.Interface(), n.Interface()) {
+			continue
+		}
+		e := edit{key: strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]}
+		if !isEmpty(n) {
+			e.value = n.Interface()
+		}
+		edits = append(edits, e)
+	}
+	return edits
+}
+
+func isEmpty(v reflect.Value) bool {
+	if v.Kind() == reflect.Slice {
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// ---- YAML ----
+
+// editYAML rewrites only the lines of the top-level keys being changed;
+// every other line is copied through untouched.
+func editYAML(data []byte, edits []edit) ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("front-matter is not a key/value mapping")
+	}
+	if root.Style&yaml.FlowStyle != 0 {
+		return nil, fmt.Errorf("flow-style front-matter ({...}) can't be edited in place")
+	}
+
+	src := lines(data)
+	indent := yamlIndent(src)
+
+	// Lines [start, end) of each top-level key, trailing blank and
+	// comment lines excluded: those belong to whatever comes next.
+	type span struct {
+		start, end int
+		key, value *yaml.Node
+	}
+	spans := map[string]span{}
+	insertAt := len(src) // new keys go after the last one
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		k := root.Content[i]
+		end := len(src)
+		if i+2 < len(root.Content) {
+			end = root.Content[i+2].Line - 1
+		}
+		for end > k.Line && isBlankOrComment(src[end-1]) {
+			end--
+		}
+		spans[k.Value] = span{start: k.Line - 1, end: end, key: k, value: root.Content[i+1]}
+		insertAt = end
+	}
+
+	replace := map[int]string{}
+	skipTo := map[int]int{}
+	var appended strings.Builder
+	for _, e := range edits {
+		s, ok := spans[e.key]
+		switch {
+		case ok && e.value == nil:
+			replace[s.start], skipTo[s.start] = "", s.end
+		case ok:
+			text, err := yamlKey(e.key, e.value, s.key, s.value, indent)
+			if err != nil {
+				return nil, err
+			}
+			replace[s.start], skipTo[s.start] = text, s.end
+		case e.value != nil:
+			text, err := yamlKey(e.key, e.value, nil, nil, indent)
+			if err != nil {
+				return nil, err
+			}
+			appended.WriteString(text)
+		}
+	}
+
+	return splice(src, replace, skipTo, insertAt, appended.String()), nil
+}
+
+// splice copies src, swapping in replace[i] for lines [i, skipTo[i]) and
+// inserting extra before line insertAt.
+func splice(src []string, replace map[int]string, skipTo map[int]int, insertAt int, extra string) []byte {
+	var out strings.Builder
+	for i := 0; i <= len(src); i++ {
+		if i == insertAt {
+			if out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+				out.WriteString("\n")
+			}
+			out.WriteString(extra)
+		}
+		if i == len(src) {
+			break
+		}
+		if text, ok := replace[i]; ok {
+			out.WriteString(text)
+			i = skipTo[i] - 1
+			continue
+		}
+		out.WriteString(src[i])
+	}
+	return []byte(out.String())
+}
+
+// yamlKey renders "key: value", keeping the quoting, list style and line
+// comment of the value it replaces.
+func yamlKey(key string, value any, oldKey, oldValue *yaml.Node, indent int) (string, error) {
+	var v yaml.Node
+	if err := v.Encode(value); err != nil {
+		return "", err
+	}
+	k := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	if oldKey != nil {
+		k.Style = oldKey.Style
+		k.LineComment = oldKey.LineComment
+	}
+	if oldValue != nil {
+		inheritStyle(&v, oldValue)
+		v.LineComment = oldValue.LineComment
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+	if err := enc.Encode(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{k, &v}}); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func inheritStyle(n, old *yaml.Node) {
+	if n.Kind != old.Kind {
+		return
+	}
+	switch n.Kind {
+	case yaml.ScalarNode:
+		// plain stays plain unless the new value needs quotes; the encoder
+		// decides that
+		if old.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+			n.Style = old.Style
+		}
+	case yaml.SequenceNode:
+		n.Style = old.Style & yaml.FlowStyle
+		if len(old.Content) > 0 {
+			for _, c := range n.Content {
+				inheritStyle(c, old.Content[0])
+			}
+		}
+	}
+}
+
+func isBlankOrComment(line string) bool {
+	t := strings.TrimSpace(line)
+	return t == "" || strings.HasPrefix(line, "#")
+}
+
+// yamlIndent guesses the indentation the file uses for nested lines.
+func yamlIndent(src []string) int {
+	for _, l := range src {
+		if t := strings.TrimLeft(l, " "); t != l && strings.TrimSpace(t) != "" && !strings.HasPrefix(t, "#") {
+			return len(l) - len(t)
+		}
+	}
+	return 2
+}
+
+// ---- TOML ----
+
+// editTOML edits top-level key = value lines (those before the first
+// [table]) and leaves every other line alone.
+func editTOML(data []byte, edits []edit) ([]byte, error) {
+	src := lines(data)
+	top := len(src)
+	for i, l := range src {
+		if strings.HasPrefix(strings.TrimSpace(l), "[") {
+			top = i
+			break
+		}
+	}
+
+	type span struct{ start, end int }
+	spans := map[string]span{}
+	for i := 0; i < top; i++ {
+		key, rest, ok := tomlKeyLine(src[i])
+		if !ok {
+			continue
+		}
+		end := i + 1
+		for open := tomlOpen(rest); open && end < top; end++ {
+			open = tomlOpen(strings.Join(src[i:end+1], ""))
+		}
+		spans[key] = span{i, end}
+		i = end - 1
+	}
+
+	replace := map[int]string{}
+	skipTo := map[int]int{}
+	var appended strings.Builder
+	for _, e := range edits {
+		s, ok := spans[e.key]
+		switch {
+		case ok && e.value == nil:
+			replace[s.start], skipTo[s.start] = "", s.end
+		case ok:
+			old := strings.Join(src[s.start:s.end], "")
+			replace[s.start], skipTo[s.start] = tomlKey(e.key, e.value, old), s.end
+		case e.value != nil:
+			appended.WriteString(tomlKey(e.key, e.value, ""))
+		}
+	}
+
+	// new keys go after the last top-level line, before any [table]
+	insertAt := top
+	for insertAt > 0 && strings.TrimSpace(src[insertAt-1]) == "" {
+		insertAt--
+	}
+
+	return splice(src, replace, skipTo, insertAt, appended.String()), nil
+}
+
+func tomlKeyLine(line string) (key, rest string, ok bool) {
+	t := strings.TrimSpace(line)
+	if t == "" || strings.HasPrefix(t, "#") {
+		return "", "", false
+	}
+	k, rest, ok := strings.Cut(t, "=")
+	if !ok {
+		return "", "", false
+	}
+	k = strings.TrimSpace(k)
+	if uq, err := strconv.Unquote(k); err == nil {
+		k = uq
+	} else {
+		k = strings.Trim(k, "'")
+	}
+	return k, rest, true
+}
+
+// tomlOpen reports whether a value continues on the next line: an
+// unclosed array or multi-line string.
+func tomlOpen(s string) bool {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case strings.HasPrefix(s[i:], `"""`) || strings.HasPrefix(s[i:], `'''`):
+			q := s[i : i+3]
+			j := strings.Index(s[i+3:], q)
+			if j < 0 {
+				return true
+			}
+			i += j + 5
+		case c == '"' || c == '\'':
+			for i++; i < len(s) && s[i] != c && s[i] != '\n'; i++ {
+				if c == '"' && s[i] == '\\' {
+					i++
+				}
+			}
+		case c == '#':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// tomlKey renders "key = value", keeping a trailing comment, single
+// quotes and multi-line arrays from the line it replaces.
+func tomlKey(key string, value any, old string) string {
+	_, oldValue, _ := strings.Cut(old, "=")
+	oldValue = strings.TrimSpace(oldValue)
+	literal := strings.HasPrefix(oldValue, "'")
+	multiline := strings.Contains(oldValue, "\n")
+
+	var v string
+	switch x := value.(type) {
+	case string:
+		v = tomlString(x, literal)
+	case bool:
+		v = strconv.FormatBool(x)
+	case []string:
+		items := make([]string, len(x))
+		literal = strings.HasPrefix(strings.TrimLeft(oldValue, "[ \n\t"), "'")
+		for i, s := range x {
+			items[i] = tomlString(s, literal)
+		}
+		if multiline {
+			pad := "    "
+			if _, rest, ok := strings.Cut(oldValue, "\n"); ok {
+				pad = rest[:len(rest)-len(strings.TrimLeft(rest, " \t"))]
+			}
+			v = "[\n" + pad + strings.Join(items, ",\n"+pad) + ",\n]"
+		} else {
+			v = "[" + strings.Join(items, ", ") + "]"
+		}
+	default:
+		v = tomlString(fmt.Sprint(x), false)
+	}
+
+	line := key + " = " + v
+	if !multiline {
+		if c := tomlComment(oldValue); c != "" {
+			line += " " + c
+		}
+	}
+	return line + "\n"
+}
+
+func tomlString(s string, literal bool) string {
+	if literal && !strings.ContainsAny(s, "'\n") {
+		return "'" + s + "'"
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// tomlComment returns the "# …" at the end of a single-line value.
+func tomlComment(value string) string {
+	var quote byte
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0 && c == '\\' && quote == '"':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return strings.TrimSpace(value[i:])
+		}
+	}
+	return ""
+}
+
+// ---- JSON ----
+
+// editJSON keeps the order and raw text of every member it doesn't change.
+// JSON has no comments, so that is all there is to preserve.
+func editJSON(data []byte, edits []edit) ([]byte, error) {
+	type member struct {
+		key   string
+		value json.RawMessage
+	}
+	var members []member
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("front-matter is not a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var m member
+		m.key, _ = tok.(string)
+		if err := dec.Decode(&m.value); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	indent := "  "
+	if src := lines(data); len(src) > 1 {
+		if t := strings.TrimLeft(src[1], " \t"); t != src[1] {
+			indent = src[1][:len(src[1])-len(t)]
+		}
+	}
+
+	for _, e := range edits {
+		i := 0
+		for i < len(members) && members[i].key != e.key {
+			i++
+		}
+		if e.value == nil {
+			if i < len(members) {
+				members = append(members[:i], members[i+1:]...)
+			}
+			continue
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if i < len(members) && bytes.Contains(members[i].value, []byte("\n")) {
+			enc.SetIndent(indent, indent)
+		}
+		if err := enc.Encode(e.value); err != nil {
+			return nil, err
+		}
+		value := json.RawMessage(bytes.TrimSpace(buf.Bytes()))
+		if i < len(members) {
+			members[i].value = value
+		} else {
+			members = append(members, member{e.key, value})
+		}
+	}
+
+	var out strings.Builder
+	out.WriteString("{\n")
+	for i, m := range members {
+		key, _ := json.Marshal(m.key)
+		fmt.Fprintf(&out, "%s%s: %s", indent, key, m.value)
+		if i < len(members)-1 {
+			out.WriteString(",")
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("}\n")
+	return []byte(out.String()), nil
+}
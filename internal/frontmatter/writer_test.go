@@ -0,0 +1,124 @@
+// internal/frontmatter/writer_test.go
+
+package frontmatter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestWriteFile edits a file's front-matter and checks the exact bytes
+// written, then that reading them back gives the meta and body written.
+func TestWriteFile(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		edit func(*Meta)
+		body string // replaces the body if set
+		want string
+	}{
+		{
+			name: "no change is byte for byte",
+			in:   "---\n# a comment\ntitle:   'Hello'\n---\n\n\nBody.\n",
+			edit: func(m *Meta) {},
+			want: "---\n# a comment\ntitle:   'Hello'\n---\n\n\nBody.\n",
+		},
+		{
+			name: "yaml keeps comments, quoting and unknown keys",
+			in:   "---\n# a comment\ntitle: 'Hello'   # keep me\ntags: [a, b]\nextra: kept\n---\n\nBody.\n",
+			edit: func(m *Meta) { m.PostID, m.Hash = "abc", "h1" },
+			want: "---\n# a comment\ntitle: 'Hello'   # keep me\ntags: [a, b]\nextra: kept\npost_id: abc\nhash: h1\n---\n\nBody.\n",
+		},
+		{
+			name: "yaml block list edited, cleared key removed",
+			in:   "---\ntitle: Hello\ntags:\n  - a\n  - b\npost_id: old\n---\nBody.\n",
+			edit: func(m *Meta) { m.Tags, m.PostID = []string{"a", "c"}, "" },
+			want: "---\ntitle: Hello\ntags:\n  - a\n  - c\n---\nBody.\n",
+		},
+		{
+			name: "yaml multi-line value replaced, false removed",
+			in:   "---\ntitle: Hello\nfeatured: true\ncustom_excerpt: |\n  line one\n  line two\nstatus: draft\n---\nBody.\n",
+			edit: func(m *Meta) { m.Featured, m.CustomExcerpt, m.Status = false, "new: excerpt", "published" },
+			want: "---\ntitle: Hello\ncustom_excerpt: 'new: excerpt'\nstatus: published\n---\nBody.\n",
+		},
+		{
+			name: "yaml keeps double quotes where needed",
+			in:   "---\ntitle: \"Hello\"\nslug: hello\n---\nBody.\n",
+			edit: func(m *Meta) { m.Title, m.Slug = "Yes: no # maybe", "" },
+			want: "---\ntitle: \"Yes: no # maybe\"\n---\nBody.\n",
+		},
+		{
+			name: "yaml map",
+			in:   "---\ntitle: Hello\nmarkdown:\n  gfm: true\n---\nBody.\n",
+			edit: func(m *Meta) { m.Markdown = map[string]bool{"gfm": false, "footnotes": true} },
+			want: "---\ntitle: Hello\nmarkdown:\n  footnotes: true\n  gfm: false\n---\nBody.\n",
+		},
+		{
+			name: "toml keeps comments and tables",
+			in:   "+++\ntitle = \"Hello\" # c\ntags = [\"a\"]\n[extra]\nx = 1\n+++\nBody.\n",
+			edit: func(m *Meta) { m.PostID, m.Tags, m.Title = "abc", []string{"a", "b"}, `It's "new"` },
+			want: "+++\ntitle = \"It's \\\"new\\\"\" # c\ntags = [\"a\", \"b\"]\npost_id = \"abc\"\n[extra]\nx = 1\n+++\nBody.\n",
+		},
+		{
+			name: "json in delimiters keeps unknown keys",
+			in:   "---json\n{\n  \"title\": \"Hello\",\n  \"unknown\": 1\n}\n---\nBody.\n",
+			edit: func(m *Meta) { m.PostID, m.Title = "abc", "Hi" },
+			want: "---json\n{\n  \"title\": \"Hi\",\n  \"unknown\": 1,\n  \"post_id\": \"abc\"\n}\n---\nBody.\n",
+		},
+		{
+			name: "bare json",
+			in:   "{\n  \"title\": \"Hello\"\n}\n\nBody.\n",
+			edit: func(m *Meta) { m.Slug = "s" },
+			want: "{\n  \"title\": \"Hello\",\n  \"slug\": \"s\"\n}\n\nBody.\n",
+		},
+		{
+			name: "no front-matter gets yaml",
+			in:   "Just a body.\n",
+			edit: func(m *Meta) { m.Title, m.PostID = "T", "abc" },
+			want: "---\ntitle: T\npost_id: abc\n---\n\nJust a body.\n",
+		},
+		{
+			name: "new body gets one blank line",
+			in:   "---\ntitle: Hello\n---\nOld body.\n",
+			edit: func(m *Meta) {},
+			body: "\n\n\nNew body.\n",
+			want: "---\ntitle: Hello\n---\n\nNew body.\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "post.md")
+			if err := os.WriteFile(path, []byte(tt.in), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			meta, body, err := ParseFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.edit(&meta)
+			if tt.body != "" {
+				body = []byte(tt.body)
+			}
+			if err := WriteFile(path, meta, body); err != nil {
+				t.Fatal(err)
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(raw) != tt.want {
+				t.Errorf("wrote\n%q\nwant\n%q", raw, tt.want)
+			}
+			got, _, err := ParseFile(path)
+			if err != nil {
+				t.Fatalf("reading it back: %v", err)
+			}
+			if !reflect.DeepEqual(got, meta) {
+				t.Errorf("read back\n%+v\nwant\n%+v", got, meta)
+			}
+		})
+	}
+}
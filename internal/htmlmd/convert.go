@@ -0,0 +1,399 @@
+// internal/htmlmd/convert.go
+
+package htmlmd
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Convert turns the HTML Ghost renders for a post back into Markdown.
+// Anything without a Markdown equivalent (cards, embeds, tables…) is kept
+// as raw HTML so that nothing is lost.
+func Convert(src string) (string, error) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(src), body)
+	if err != nil {
+		return "", err
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	md := strings.Join(blocks(body), "\n\n")
+	if md == "" {
+		return "", nil
+	}
+	return md + "\n", nil
+}
+
+// blocks converts the children of n, grouping runs of inline content into
+// paragraphs.
+func blocks(n *html.Node) []string {
+	var out []string
+	var para strings.Builder
+	flush := func() {
+		if p := paragraph(para.String()); p != "" {
+			out = append(out, p)
+		}
+		para.Reset()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if isBlock(c) {
+			flush()
+			if b := block(c); b != "" {
+				out = append(out, b)
+			}
+			continue
+		}
+		para.WriteString(inline(c))
+	}
+	flush()
+	return out
+}
+
+func isBlock(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return n.Type == html.CommentNode
+	}
+	switch n.DataAtom {
+	case atom.P, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Ul, atom.Ol, atom.Blockquote, atom.Pre, atom.Hr, atom.Figure,
+		atom.Div, atom.Section, atom.Table, atom.Iframe, atom.Video, atom.Audio,
+		atom.Details, atom.Dl, atom.Script, atom.Style:
+		return true
+	}
+	return false
+}
+
+func block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.P:
+		return paragraph(inlineChildren(n))
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		return strings.Repeat("#", level) + " " + strings.TrimSpace(collapse(inlineChildren(n)))
+	case atom.Ul, atom.Ol:
+		return list(n)
+	case atom.Blockquote:
+		inner := strings.Join(blocks(n), "\n\n")
+		return prefixLines(inner, "> ", ">")
+	case atom.Pre:
+		return codeBlock(n)
+	case atom.Hr:
+		return "---"
+	case atom.Figure:
+		if md, ok := imageFigure(n); ok {
+			return md
+		}
+	case atom.Div, atom.Section:
+		if !hasClassPrefix(n, "kg-") {
+			return strings.Join(blocks(n), "\n\n")
+		}
+	}
+	return raw(n)
+}
+
+// list renders <ul>/<ol>, indenting continuation lines under the marker.
+func list(n *html.Node) string {
+	num := 1
+	if s, err := strconv.Atoi(attr(n, "start")); err == nil {
+		num = s
+	}
+	var items []string
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", num)
+			num++
+		}
+		body := ""
+		for i, b := range blocks(li) {
+			switch {
+			case i == 0:
+			case isListMarker(b): // nested lists stay tight
+				body += "\n"
+			default:
+				body += "\n\n"
+			}
+			body += b
+		}
+		indent := strings.Repeat(" ", len(marker))
+		items = append(items, marker+prefixRest(body, indent))
+	}
+	return strings.Join(items, "\n")
+}
+
+// isListMarker reports whether md starts with a list item. Paragraph text
+// never does, because escapeLineStart escapes it.
+func isListMarker(md string) bool {
+	if strings.HasPrefix(md, "- ") {
+		return true
+	}
+	i := strings.IndexFunc(md, func(r rune) bool { return !unicode.IsDigit(r) })
+	return i > 0 && strings.HasPrefix(md[i:], ". ")
+}
+
+func codeBlock(n *html.Node) string {
+	code, lang := n, ""
+	if c := n.FirstChild; c != nil && c.DataAtom == atom.Code && c.NextSibling == nil {
+		code = c
+		for _, cls := range strings.Fields(attr(c, "class")) {
+			if l, ok := strings.CutPrefix(cls, "language-"); ok {
+				lang = l
+			}
+		}
+	}
+	text := strings.TrimSuffix(textContent(code), "\n")
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + text + "\n" + fence
+}
+
+// imageFigure turns Ghost's image card into ![alt](src "caption"). Cards
+// with any other content stay HTML.
+func imageFigure(n *html.Node) (string, bool) {
+	if !hasClass(n, "kg-image-card") {
+		return "", false
+	}
+	var img, caption *html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.DataAtom == atom.Img:
+			img = c
+		case c.DataAtom == atom.Figcaption:
+			caption = c
+		case c.Type == html.TextNode && strings.TrimSpace(c.Data) == "":
+		default:
+			return "", false
+		}
+	}
+	if img == nil {
+		return "", false
+	}
+	title := ""
+	if caption != nil {
+		title = strings.TrimSpace(textContent(caption))
+	}
+	return image(attr(img, "alt"), attr(img, "src"), title), true
+}
+
+func inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(inline(c))
+	}
+	return b.String()
+}
+
+func inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return escape(collapse(n.Data))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Strong, atom.B:
+		return wrap(inlineChildren(n), "**")
+	case atom.Em, atom.I:
+		return wrap(inlineChildren(n), "*")
+	case atom.S, atom.Del, atom.Strike:
+		return wrap(inlineChildren(n), "~~")
+	case atom.Code:
+		text := textContent(n)
+		tick := "`"
+		for strings.Contains(text, tick) {
+			tick += "`"
+		}
+		if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+			text = " " + text + " "
+		}
+		return tick + text + tick
+	case atom.A:
+		href := attr(n, "href")
+		if href == "" {
+			return inlineChildren(n)
+		}
+		return "[" + strings.TrimSpace(inlineChildren(n)) + "](" + destination(href, attr(n, "title")) + ")"
+	case atom.Img:
+		return image(attr(n, "alt"), attr(n, "src"), attr(n, "title"))
+	case atom.Br:
+		return "\\\n"
+	case atom.Span:
+		return inlineChildren(n)
+	}
+	return raw(n)
+}
+
+func image(alt, src, title string) string {
+	return "![" + escape(alt) + "](" + destination(src, title) + ")"
+}
+
+func destination(url, title string) string {
+	if strings.ContainsAny(url, " ()") {
+		url = "<" + url + ">"
+	}
+	if title != "" {
+		url += ` "` + strings.ReplaceAll(title, `"`, `\"`) + `"`
+	}
+	return url
+}
+
+// wrap puts delimiters around s, keeping surrounding spaces outside so the
+// emphasis stays valid Markdown.
+func wrap(s, delim string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	lead := s[:strings.Index(s, trimmed)]
+	trail := s[len(lead)+len(trimmed):]
+	return lead + delim + trimmed + delim + trail
+}
+
+// paragraph tidies inline Markdown and escapes anything at the start of a
+// line that would otherwise be read as a block marker.
+func paragraph(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		l = strings.TrimSpace(collapse(l))
+		if i < len(lines)-1 && !strings.HasSuffix(l, "\\") {
+			l += " "
+		}
+		lines[i] = escapeLineStart(l)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func escapeLineStart(l string) string {
+	switch {
+	case l == "":
+		return l
+	case strings.ContainsRune("#>+-=", rune(l[0])):
+		return "\\" + l
+	}
+	// "1. " would start an ordered list
+	if i := strings.IndexFunc(l, func(r rune) bool { return !unicode.IsDigit(r) }); i > 0 &&
+		strings.HasPrefix(l[i:], ". ") {
+		return l[:i] + "\\" + l[i:]
+	}
+	return l
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	rs := []rune(s)
+	for i, r := range rs {
+		switch r {
+		case '\\', '*', '`', '[', ']', '<':
+			b.WriteRune('\\')
+		case '_':
+			// intraword underscores never start emphasis
+			if i == 0 || i == len(rs)-1 || !isWord(rs[i-1]) || !isWord(rs[i+1]) {
+				b.WriteRune('\\')
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isWord(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+
+// collapse squashes runs of whitespace the way a browser would.
+func collapse(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == '\n' || r == '\t' || r == ' ' || r == '\r' {
+			if !space {
+				b.WriteRune(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func prefixLines(s, prefix, blank string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if l == "" {
+			lines[i] = blank
+		} else {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// prefixRest indents every line after the first.
+func prefixRest(s, indent string) string {
+	first, rest, ok := strings.Cut(s, "\n")
+	if !ok {
+		return s
+	}
+	return first + "\n" + prefixLines(rest, indent, "")
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func raw(n *html.Node) string {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, n); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func hasClassPrefix(n *html.Node, prefix string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if strings.HasPrefix(c, prefix) {
+			return true
+		}
+	}
+	return false
+}
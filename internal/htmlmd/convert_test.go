@@ -0,0 +1,96 @@
+// internal/htmlmd/convert_test.go
+
+package htmlmd
+
+import (
+	"testing"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/render"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name, html, want string
+	}{
+		{"empty", "", ""},
+		{"inline formatting", "<p>Hello <b>bold</b> <i>it</i> <s>gone</s> <del>x</del></p>", "Hello **bold** *it* ~~gone~~ ~~x~~\n"},
+		{"whitespace collapses", "<p>  lots   of\n  space  </p>", "lots of space\n"},
+		{"spaces stay outside emphasis", "<p><strong> padded </strong>word</p>", "**padded** word\n"},
+		{"span is unwrapped", `<p><span style="x">in span</span></p>`, "in span\n"},
+		{"link without href", "<p><a>no href</a></p>", "no href\n"},
+		{"destination with spaces", `<p><a href="https://e.com/a (1).png">paren</a></p>`, "[paren](<https://e.com/a (1).png>)\n"},
+		{"title with quotes", `<p><img src="a.png" alt="x" title='say "hi"'></p>`, `![x](a.png "say \"hi\"")` + "\n"},
+		{"image card", `<figure class="kg-card kg-image-card"><img src="https://e.com/a.png" alt="A"><figcaption><span>Cap</span> tion</figcaption></figure>`, `![A](https://e.com/a.png "Cap tion")` + "\n"},
+		{"other cards stay HTML", `<figure class="kg-card kg-gallery-card"><div>x</div></figure>`, `<figure class="kg-card kg-gallery-card"><div>x</div></figure>` + "\n"},
+		{"plain div is unwrapped", "<div><p>plain div</p></div>", "plain div\n"},
+		{"embeds stay HTML", `<iframe src="https://youtube.com/embed/x"></iframe>`, `<iframe src="https://youtube.com/embed/x"></iframe>` + "\n"},
+		{"tables stay HTML", "<table><tr><td>1</td></tr></table>", "<table><tbody><tr><td>1</td></tr></tbody></table>\n"},
+		{"block markers are escaped", "<p>+ plus</p><p>= eq</p><p>> gt</p><p>12. num</p>", "\\+ plus\n\n\\= eq\n\n\\> gt\n\n12\\. num\n"},
+		{"loose inline content is a paragraph", "loose <em>inline</em> text<p>then para</p>", "loose *inline* text\n\nthen para\n"},
+		{"code language", `<pre><code class="lang-x language-py">print(1)` + "\n</code></pre>", "```py\nprint(1)\n```\n"},
+		{"code with backticks", "<p><code>`tick`</code></p>", "`` `tick` ``\n"},
+		{"ordered list start and nesting", `<ol start="9"><li>nine</li><li>ten<ul><li>sub</li></ul></li></ol>`, "9. nine\n10. ten\n    - sub\n"},
+		{"list in a quote", "<blockquote><p>a</p><ul><li>b</li></ul></blockquote>", "> a\n>\n> - b\n"},
+		{"underscores", "<p>under_score _lead trail_ mid_dle</p>", "under_score \\_lead trail\\_ mid_dle\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert(tt.html)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Convert(%q)\n got %q\nwant %q", tt.html, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRoundTrip renders Markdown as publish does, converts it back as pull
+// does, and expects the same Markdown, or where Markdown has more than one
+// spelling, the same HTML: a pulled post must not republish.
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name, md string
+		sameMD   bool
+	}{
+		{"heading and inlines", "# Title\n\nA paragraph with **bold**, *italic* and `code`.\n", true},
+		{"nested list", "- one\n- two\n  - nested\n- three\n", true},
+		{"ordered list start", "3. three\n4. four\n", true},
+		{"loose list", "- item\n\n  with a second paragraph\n- next\n", true},
+		{"quote", "> quoted\n>\n> two paragraphs\n", true},
+		{"code", "```go\nfunc main() {}\n```\n", true},
+		{"fence in code", "````\n```\nnested\n```\n````\n", true},
+		{"links and images", "A [link](https://example.com \"Title\") and ![alt](https://example.com/a.png).\n", true},
+		{"link with emphasis", "A link with [*emphasis*](https://e.com/) inside.\n", true},
+		{"image card with caption", "![cap img](https://example.com/a.png \"A caption\")\n", true},
+		{"escaped block markers", "\\# not a heading\n\n\\- not a list\n\n1\\. not a list\n", true},
+		{"hard break", "line one\\\nline two\n", true},
+		{"rule", "---\n", true},
+		{"card HTML", "<div class=\"kg-card kg-callout-card\">hi</div>\n", true},
+		{"escapes", "Escapes: 1\\*2, a\\_b\\_, snake_case, \\[x\\], \\<tag\\>, back\\\\slash.\n", false},
+		{"code with a backtick", "Code with `` a`b `` tick.\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := render.HTML([]byte(tt.md), render.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			md, err := Convert(html)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.sameMD && md != tt.md {
+				t.Errorf("Markdown changed\n got %q\nwant %q", md, tt.md)
+			}
+			again, err := render.HTML([]byte(md), render.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again != html {
+				t.Errorf("HTML changed after converting back to %q\n got %q\nwant %q", md, again, html)
+			}
+		})
+	}
+}
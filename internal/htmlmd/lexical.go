@@ -0,0 +1,374 @@
+// internal/htmlmd/lexical.go
+
+package htmlmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// FromLexical turns a post's Lexical document, Ghost's editor format, back
+// into Markdown. Image and gallery cards become image lines, the cards the
+// renderer makes from shortcodes become shortcodes again, and HTML and
+// Markdown cards give their content. ok is false if the document holds a
+// node with no Markdown form (an embed, a signup card, an uploaded audio
+// file…); the post's HTML is then the better source.
+func FromLexical(src string) (md string, ok bool, err error) {
+	var doc struct {
+		Root lexNode `json:"root"`
+	}
+	if err := json.Unmarshal([]byte(src), &doc); err != nil {
+		return "", false, fmt.Errorf("reading Lexical: %w", err)
+	}
+	var out []string
+	for _, n := range doc.Root.children() {
+		b, ok := lexBlock(n)
+		if !ok {
+			return "", false, nil
+		}
+		if b != "" {
+			out = append(out, b)
+		}
+	}
+	if len(out) == 0 {
+		return "", true, nil
+	}
+	return strings.Join(out, "\n\n") + "\n", true, nil
+}
+
+// lexNode is one node of a Lexical document.
+type lexNode map[string]any
+
+func (n lexNode) str(key string) string {
+	s, _ := n[key].(string)
+	return s
+}
+
+func (n lexNode) num(key string) int {
+	f, _ := n[key].(float64)
+	return int(f)
+}
+
+func (n lexNode) flag(key string) bool {
+	b, _ := n[key].(bool)
+	return b
+}
+
+func (n lexNode) children() []lexNode {
+	list, _ := n["children"].([]any)
+	out := make([]lexNode, 0, len(list))
+	for _, c := range list {
+		if m, ok := c.(map[string]any); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func lexBlock(n lexNode) (string, bool) {
+	switch n.str("type") {
+	case "paragraph":
+		in, ok := lexInline(n.children())
+		if !ok {
+			return "", false
+		}
+		return fromHTML("<p>" + in + "</p>")
+	case "heading", "extended-heading":
+		tag := n.str("tag")
+		in, ok := lexInline(n.children())
+		if !ok || len(tag) != 2 || tag[0] != 'h' || tag[1] < '1' || tag[1] > '6' {
+			return "", false
+		}
+		return fromHTML("<" + tag + ">" + in + "</" + tag + ">")
+	case "quote", "extended-quote":
+		in, ok := lexInline(n.children())
+		if !ok {
+			return "", false
+		}
+		// paragraphs are kept apart by a blank line
+		return fromHTML("<blockquote><p>" + strings.ReplaceAll(in, "<br><br>", "</p><p>") + "</p></blockquote>")
+	case "list":
+		list, ok := lexList(n)
+		if !ok {
+			return "", false
+		}
+		return fromHTML(list)
+	case "codeblock":
+		code := n.str("code")
+		fence := "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		info := n.str("language")
+		if caption := plain(n.str("caption")); caption != "" {
+			a, ok := attrs([][2]string{{"title", caption}})
+			if !ok {
+				return "", false
+			}
+			info += " {" + a + "}"
+		}
+		return fence + info + "\n" + code + "\n" + fence, true
+	case "horizontalrule":
+		return "---", true
+	case "html":
+		return strings.TrimSpace(n.str("html")), true
+	case "markdown":
+		return strings.TrimSpace(n.str("markdown")), true
+	case "image":
+		return lexImage(n)
+	case "gallery":
+		return lexGallery(n)
+	case "callout", "toggle", "button", "bookmark", "header", "product":
+		return lexShortcode(n)
+	}
+	return "", false
+}
+
+// lexInline writes inline nodes as the HTML Convert reads them.
+func lexInline(nodes []lexNode) (string, bool) {
+	var b strings.Builder
+	for _, n := range nodes {
+		switch n.str("type") {
+		case "text", "extended-text":
+			s := html.EscapeString(n.str("text"))
+			format := n.num("format")
+			for _, f := range []struct {
+				bit int
+				tag string
+			}{{16, "code"}, {1, "strong"}, {2, "em"}, {4, "s"}, {8, "u"}, {32, "sub"}, {64, "sup"}, {128, "mark"}} {
+				if format&f.bit != 0 {
+					s = "<" + f.tag + ">" + s + "</" + f.tag + ">"
+				}
+			}
+			b.WriteString(s)
+		case "link":
+			in, ok := lexInline(n.children())
+			if !ok {
+				return "", false
+			}
+			b.WriteString(`<a href="` + html.EscapeString(n.str("url")) + `"`)
+			if t := n.str("title"); t != "" {
+				b.WriteString(` title="` + html.EscapeString(t) + `"`)
+			}
+			b.WriteString(">" + in + "</a>")
+		case "linebreak":
+			b.WriteString("<br>")
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// lexList writes a list as HTML. Lexical keeps a nested list in a list
+// item of its own, after the item it belongs to.
+func lexList(n lexNode) (string, bool) {
+	tag := "ul"
+	switch n.str("listType") {
+	case "bullet":
+	case "number":
+		tag = "ol"
+	default:
+		return "", false
+	}
+	var items []string
+	for _, it := range n.children() {
+		if it.str("type") != "listitem" {
+			return "", false
+		}
+		if c := it.children(); len(c) == 1 && c[0].str("type") == "list" && len(items) > 0 {
+			sub, ok := lexList(c[0])
+			if !ok {
+				return "", false
+			}
+			items[len(items)-1] += sub
+			continue
+		}
+		in, ok := lexInline(it.children())
+		if !ok {
+			return "", false
+		}
+		items = append(items, in)
+	}
+	open := "<" + tag + ">"
+	if start := n.num("start"); tag == "ol" && start > 1 {
+		open = fmt.Sprintf(`<ol start="%d">`, start)
+	}
+	return open + "<li>" + strings.Join(items, "</li><li>") + "</li></" + tag + ">", true
+}
+
+func lexImage(n lexNode) (string, bool) {
+	if n.str("src") == "" {
+		return "", false
+	}
+	md := image(n.str("alt"), n.str("src"), plain(n.str("caption")))
+	if href := n.str("href"); href != "" {
+		md = "[" + md + "](" + destination(href, "") + ")"
+	}
+	if w := n.str("cardWidth"); w == "wide" || w == "full" {
+		md += "{." + w + "}"
+	}
+	return md, true
+}
+
+// lexGallery writes a gallery as one image per line, which the renderer
+// reads back as a gallery.
+func lexGallery(n lexNode) (string, bool) {
+	list, _ := n["images"].([]any)
+	if len(list) < 2 {
+		return "", false
+	}
+	var lines []string
+	for _, p := range list {
+		pic, ok := p.(map[string]any)
+		if !ok || lexNode(pic).str("src") == "" {
+			return "", false
+		}
+		img := lexNode(pic)
+		line := image(img.str("alt"), img.str("src"), img.str("title"))
+		if href := img.str("href"); href != "" {
+			line = "[" + line + "](" + destination(href, "") + ")"
+		}
+		lines = append(lines, line)
+	}
+	if caption := plain(n.str("caption")); caption != "" {
+		a, ok := attrs([][2]string{{"caption", caption}})
+		if !ok {
+			return "", false
+		}
+		lines = append(lines, "{"+a+"}")
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+// lexShortcode writes a card as the shortcode that makes it, leaving out
+// attributes that hold the renderer's defaults.
+func lexShortcode(n lexNode) (string, bool) {
+	name := n.str("type")
+	var a [][2]string
+	body := ""
+	switch name {
+	case "callout":
+		color := n.str("backgroundColor")
+		if color == "grey" {
+			color = ""
+		}
+		a = [][2]string{{"emoji", n.str("calloutEmoji")}, {"color", color}}
+		body = n.str("calloutText")
+	case "toggle":
+		a = [][2]string{{"heading", plain(n.str("heading"))}}
+		body = n.str("content")
+	case "button":
+		align := n.str("alignment")
+		if align == "left" {
+			align = ""
+		}
+		a = [][2]string{{"url", n.str("buttonUrl")}, {"text", n.str("buttonText")}, {"align", align}}
+	case "bookmark":
+		meta, _ := n["metadata"].(map[string]any)
+		m := lexNode(meta)
+		a = [][2]string{
+			{"url", n.str("url")}, {"title", m.str("title")}, {"description", m.str("description")},
+			{"icon", m.str("icon")}, {"thumbnail", m.str("thumbnail")}, {"author", m.str("author")},
+			{"publisher", m.str("publisher")}, {"caption", plain(n.str("caption"))},
+		}
+	case "header":
+		size, style := n.str("size"), n.str("style")
+		if size == "small" {
+			size = ""
+		}
+		if style == "dark" {
+			style = ""
+		}
+		a = [][2]string{
+			{"heading", plain(n.str("header"))}, {"subheading", plain(n.str("subheader"))},
+			{"size", size}, {"style", style}, {"background_image", n.str("backgroundImageSrc")},
+		}
+		if n.flag("buttonEnabled") {
+			a = append(a, [2]string{"button_text", n.str("buttonText")}, [2]string{"button_url", n.str("buttonUrl")})
+		}
+	case "product":
+		a = [][2]string{{"title", plain(n.str("productTitle"))}, {"image", n.str("productImageSrc")}}
+		if n.flag("productRatingEnabled") {
+			a = append(a, [2]string{"rating", strconv.Itoa(n.num("productStarRating"))})
+		}
+		if n.flag("productButtonEnabled") {
+			a = append(a, [2]string{"button_text", n.str("productButton")}, [2]string{"button_url", n.str("productUrl")})
+		}
+		body = n.str("productDescription")
+	}
+
+	list, ok := attrs(a)
+	if !ok {
+		return "", false
+	}
+	inner, err := Convert(body)
+	if err != nil {
+		return "", false
+	}
+	inner = strings.TrimSuffix(inner, "\n")
+	// a shortcode inside this one needs a longer fence
+	fence := ":::"
+	for _, l := range strings.Split(inner, "\n") {
+		if strings.HasPrefix(l, fence) {
+			fence = strings.Repeat(":", len(l)-len(strings.TrimLeft(l, ":"))+1)
+		}
+	}
+	open := fence + name
+	if list != "" {
+		open += "{" + list + "}"
+	}
+	if inner == "" {
+		return open + "\n" + fence, true
+	}
+	return open + "\n" + inner + "\n" + fence, true
+}
+
+// attrs writes key="value" pairs, skipping empty values. It reports false
+// if a value holds both kinds of quote, which no attribute can.
+func attrs(pairs [][2]string) (string, bool) {
+	var out []string
+	for _, p := range pairs {
+		k, v := p[0], p[1]
+		switch {
+		case v == "":
+			continue
+		case !strings.Contains(v, `"`):
+			out = append(out, k+`="`+v+`"`)
+		case !strings.Contains(v, "'"):
+			out = append(out, k+"='"+v+"'")
+		default:
+			return "", false
+		}
+	}
+	return strings.Join(out, " "), true
+}
+
+// fromHTML converts one block of HTML built from Lexical nodes.
+func fromHTML(s string) (string, bool) {
+	md, err := Convert(s)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSuffix(md, "\n"), true
+}
+
+// plain is the text of an HTML snippet, such as a caption, on one line.
+func plain(s string) string {
+	body := &nethtml.Node{Type: nethtml.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := nethtml.ParseFragment(strings.NewReader(s), body)
+	if err != nil {
+		return html.UnescapeString(s)
+	}
+	var b strings.Builder
+	for _, n := range nodes {
+		b.WriteString(textContent(n))
+	}
+	return strings.TrimSpace(collapse(b.String()))
+}
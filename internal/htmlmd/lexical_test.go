@@ -0,0 +1,88 @@
+// internal/htmlmd/lexical_test.go
+
+package htmlmd
+
+import (
+	"testing"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/render"
+)
+
+func TestFromLexical(t *testing.T) {
+	doc := func(children string) string { return `{"root":{"type":"root","children":[` + children + `]}}` }
+	text := func(s string, format int) string {
+		return `{"type":"extended-text","text":"` + s + `","format":` + string(rune('0'+format)) + `}`
+	}
+	tests := []struct {
+		name, lexical, want string
+		ok                  bool
+	}{
+		{"empty", doc(""), "", true},
+		{"paragraph formats", doc(`{"type":"paragraph","children":[` + text("a", 1) + `,` + text(" b ", 0) + `,` + text("c", 2) + `]}`), "**a** b *c*\n", true},
+		{"heading", doc(`{"type":"extended-heading","tag":"h3","children":[` + text("Title", 0) + `]}`), "### Title\n", true},
+		{"quote paragraphs", doc(`{"type":"extended-quote","children":[` + text("one", 0) + `,{"type":"linebreak"},{"type":"linebreak"},` + text("two", 0) + `]}`), "> one\n>\n> two\n", true},
+		{"nested list", doc(`{"type":"list","listType":"number","start":3,"children":[{"type":"listitem","children":[` + text("three", 0) + `]},{"type":"listitem","children":[{"type":"list","listType":"bullet","children":[{"type":"listitem","children":[` + text("sub", 0) + `]}]}]}]}`), "3. three\n   - sub\n", true},
+		{"code card", doc(`{"type":"codeblock","code":"x := 1","language":"go","caption":"main.go"}`), "```go {title=\"main.go\"}\nx := 1\n```\n", true},
+		{"image card", doc(`{"type":"image","src":"https://e.com/a.png","alt":"A","caption":"<span>Cap</span>","cardWidth":"wide","href":"https://e.com"}`), "[![A](https://e.com/a.png \"Cap\")](https://e.com){.wide}\n", true},
+		{"gallery", doc(`{"type":"gallery","caption":"Both","images":[{"src":"a.png","alt":"A"},{"src":"b.png","alt":"B"}]}`), "![A](a.png)\n![B](b.png)\n{caption=\"Both\"}\n", true},
+		{"callout", doc(`{"type":"callout","calloutEmoji":"💡","backgroundColor":"grey","calloutText":"Note <b>this</b>"}`), ":::callout{emoji=\"💡\"}\nNote **this**\n:::\n", true},
+		{"toggle", doc(`{"type":"toggle","heading":"Say &quot;hi&quot;","content":"<p>Hi</p>"}`), ":::toggle{heading='Say \"hi\"'}\nHi\n:::\n", true},
+		{"button defaults left out", doc(`{"type":"button","buttonText":"Go","buttonUrl":"https://e.com","alignment":"left"}`), ":::button{url=\"https://e.com\" text=\"Go\"}\n:::\n", true},
+		{"product", doc(`{"type":"product","productTitle":"Pen","productImageSrc":"","productRatingEnabled":true,"productStarRating":4,"productButtonEnabled":false,"productDescription":"<p>Writes.</p>"}`), ":::product{title=\"Pen\" rating=\"4\"}\nWrites.\n:::\n", true},
+		{"nested shortcode gets a longer fence", doc(`{"type":"toggle","heading":"More","content":"<p>:::note</p>"}`), "::::toggle{heading=\"More\"}\n:::note\n::::\n", true},
+		{"html and markdown cards", doc(`{"type":"html","html":"<table></table>\n"},{"type":"markdown","markdown":"*md*"}`), "<table></table>\n\n*md*\n", true},
+		{"embed", doc(`{"type":"embed","html":"<iframe></iframe>"}`), "", false},
+		{"uploaded audio", doc(`{"type":"audio","src":"https://e.com/a.mp3"}`), "", false},
+		{"unknown inline", doc(`{"type":"paragraph","children":[{"type":"hashtag","text":"#x"}]}`), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := FromLexical(tt.lexical)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("FromLexical(%s)\n got %q, %v\nwant %q, %v", tt.lexical, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// TestLexicalRoundTrip renders Markdown with the lexical renderer and
+// converts the document back: a pulled post must not republish.
+func TestLexicalRoundTrip(t *testing.T) {
+	tests := []string{
+		"# Title\n\nA paragraph with **bold**, *italic* and `code`.\n",
+		"- one\n- two\n  - nested\n- three\n",
+		"> quoted\n>\n> two paragraphs\n",
+		"```go\nfunc main() {}\n```\n",
+		"![A harbour](https://e.com/h.jpg \"At dawn\"){.wide}\n",
+		"![](https://e.com/a.jpg)\n![](https://e.com/b.jpg)\n{caption=\"Two\"}\n",
+		":::callout{emoji=\"💡\" color=\"blue\"}\nA **tip**.\n:::\n",
+		"::::toggle{heading=\"Outer\"}\nText.\n\n:::button{url=\"https://e.com\" text=\"Go\" align=\"center\"}\n:::\n::::\n",
+		":::bookmark{url=\"https://e.com\" title=\"E\" description=\"An example\"}\n:::\n",
+		":::header{heading=\"Hello\" subheading=\"World\" size=\"large\" button_text=\"Join\" button_url=\"https://e.com\"}\n:::\n",
+	}
+	lexical, err := render.New("lexical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, md := range tests {
+		out, err := lexical.Render([]byte(md), render.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok, err := FromLexical(out.Lexical)
+		if err != nil || !ok {
+			t.Errorf("FromLexical of %q = %v, %v\n%s", md, ok, err, out.Lexical)
+			continue
+		}
+		again, err := lexical.Render([]byte(got), render.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.Lexical != out.Lexical {
+			t.Errorf("Lexical changed after converting %q back to %q", md, got)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// internal/images/kinds.go
+
+package images
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Kind is the Ghost upload endpoint a file goes to. Images are optimised
+// first; media and files are uploaded as they are.
+type Kind string
+
+const (
+	KindImage Kind = "images" // images/upload/
+	KindMedia Kind = "media"  // media/upload/: audio and video
+	KindFile  Kind = "files"  // files/upload/: anything else, as a download
+)
+
+var imageExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true,
+	".webp": true, ".svg": true, ".avif": true, ".ico": true,
+}
+
+var audioExts = map[string]bool{
+	".mp3": true, ".m4a": true, ".wav": true, ".ogg": true, ".oga": true,
+	".aac": true, ".flac": true, ".opus": true,
+}
+
+var videoExts = map[string]bool{
+	".mp4": true, ".m4v": true, ".webm": true, ".ogv": true, ".mov": true,
+}
+
+func ext(name string) string { return strings.ToLower(filepath.Ext(name)) }
+
+// IsImage, IsAudio and IsVideo go by the file extension.
+func IsImage(name string) bool { return imageExts[ext(name)] }
+func IsAudio(name string) bool { return audioExts[ext(name)] }
+func IsVideo(name string) bool { return videoExts[ext(name)] }
+
+// KindOf picks the endpoint for the file name.
+func KindOf(name string) Kind {
+	switch {
+	case IsImage(name):
+		return KindImage
+	case IsAudio(name), IsVideo(name):
+		return KindMedia
+	}
+	return KindFile
+}
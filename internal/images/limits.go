@@ -0,0 +1,214 @@
+// internal/images/limits.go
+
+package images
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Limits are checked before anything is sent to Ghost. The zero value
+// allows any size, the image and media types Ghost accepts by default,
+// and common document and archive types for files.
+type Limits struct {
+	MaxImage, MaxMedia, MaxFile       int64    // bytes; 0 means no limit
+	ImageTypes, MediaTypes, FileTypes []string // allowed MIME types; empty means the defaults
+}
+
+// Ghost's own defaults for images/upload/ and media/upload/.
+var (
+	defaultImageTypes = []string{
+		"image/jpeg", "image/png", "image/gif", "image/webp", "image/svg+xml",
+		"image/avif", "image/x-icon", "image/vnd.microsoft.icon",
+	}
+	defaultMediaTypes = []string{
+		"audio/mpeg", "audio/mp4", "audio/x-m4a", "audio/wav", "audio/wave",
+		"audio/x-wav", "audio/vnd.wave", "audio/ogg", "audio/aac", "audio/flac",
+		"audio/opus", "video/mp4", "video/webm", "video/ogg", "video/quicktime",
+	}
+	defaultFileTypes = []string{
+		"application/pdf", "application/zip", "application/gzip", "application/epub+zip",
+		"text/plain", "text/csv", "application/json", "application/rtf",
+		"application/msword", "application/vnd.ms-excel", "application/vnd.ms-powerpoint",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		"application/vnd.oasis.opendocument.text", "application/vnd.oasis.opendocument.spreadsheet",
+		"application/vnd.oasis.opendocument.presentation",
+	}
+)
+
+func (l Limits) max(k Kind) int64 {
+	switch k {
+	case KindImage:
+		return l.MaxImage
+	case KindMedia:
+		return l.MaxMedia
+	}
+	return l.MaxFile
+}
+
+func (l Limits) types(k Kind) []string {
+	switch k {
+	case KindImage:
+		if len(l.ImageTypes) > 0 {
+			return l.ImageTypes
+		}
+		return defaultImageTypes
+	case KindMedia:
+		if len(l.MediaTypes) > 0 {
+			return l.MediaTypes
+		}
+		return defaultMediaTypes
+	}
+	if len(l.FileTypes) > 0 {
+		return l.FileTypes
+	}
+	return defaultFileTypes
+}
+
+// allows reports whether a file called name is a type l lets through, going
+// by its extension; check sniffs the content when it is uploaded.
+func (l Limits) allows(name string) bool {
+	t := typeByExt(name)
+	for _, a := range l.types(KindOf(name)) {
+		if strings.EqualFold(a, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// check is the pre-flight check for an upload of kind k: its size, and its
+// type as sniffed from head, its first bytes. It returns the Content-Type
+// to send.
+func (l Limits) check(k Kind, name string, size int64, head []byte) (string, error) {
+	if max := l.max(k); max > 0 && size > max {
+		return "", fmt.Errorf("%s is larger than the %s limit for %s (uploads.max_%s_size)",
+			formatSize(size), formatSize(max), k, singular(k))
+	}
+	ctype := contentType(head, name)
+	for _, t := range l.types(k) {
+		if strings.EqualFold(t, ctype) {
+			return ctype, nil
+		}
+	}
+	if byExt := typeByExt(name); byExt != "" && byExt != ctype {
+		return "", fmt.Errorf("content is %s, not the %s its name suggests", ctype, byExt)
+	}
+	return "", fmt.Errorf("%s is not an allowed %s type (uploads.%s_types)", ctype, singular(k), singular(k))
+}
+
+func singular(k Kind) string {
+	if k == KindMedia {
+		return "media"
+	}
+	return strings.TrimSuffix(string(k), "s")
+}
+
+// contentType works out what a file is from its first bytes. Formats the
+// sniffer doesn't know (SVG, AVIF, M4A, MOV…) fall back to the extension,
+// but only where the content doesn't contradict it.
+func contentType(head []byte, name string) string {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	byExt := typeByExt(name)
+	switch sniffed {
+	case "application/octet-stream":
+		if byExt != "" && !strings.HasPrefix(byExt, "text/") && byExt != "image/svg+xml" {
+			return byExt
+		}
+	case "text/xml", "text/plain":
+		if byExt == "image/svg+xml" && bytes.Contains(head, []byte("<svg")) {
+			return byExt
+		}
+	case "application/ogg":
+		if byExt == "video/ogg" {
+			return byExt
+		}
+		return "audio/ogg"
+	}
+	return sniffed
+}
+
+// typeByExt maps extensions to the MIME types Ghost expects, whatever the
+// system's mime.types says.
+func typeByExt(name string) string {
+	switch ext(name) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".svg":
+		return "image/svg+xml"
+	case ".ico":
+		return "image/x-icon"
+	case ".avif":
+		return "image/avif"
+	case ".mp3":
+		return "audio/mpeg"
+	case ".m4a":
+		return "audio/mp4"
+	case ".wav":
+		return "audio/wav"
+	case ".ogg", ".oga", ".opus":
+		return "audio/ogg"
+	case ".ogv":
+		return "video/ogg"
+	case ".mov":
+		return "video/quicktime"
+	case ".m4v", ".mp4":
+		return "video/mp4"
+	}
+	t, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext(name)))
+	return t
+}
+
+// uploadError explains a failed upload: what Ghost said, and for the
+// statuses that mean the file itself was refused, why.
+func uploadError(resp *http.Response, kind Kind, size int64) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var e struct {
+		Errors []struct {
+			Message string `json:"message"`
+			Context string `json:"context"`
+		} `json:"errors"`
+	}
+	msg := ""
+	if json.Unmarshal(body, &e) == nil && len(e.Errors) > 0 {
+		msg = e.Errors[0].Message
+		if c := e.Errors[0].Context; c != "" {
+			msg += ": " + c
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusRequestEntityTooLarge:
+		return fmt.Errorf("%s is too large for the server (413): raise its upload limit, or set uploads.max_%s_size to catch this before uploading",
+			formatSize(size), singular(kind))
+	case http.StatusUnsupportedMediaType:
+		if msg == "" {
+			msg = "file type not supported"
+		}
+		return fmt.Errorf("Ghost refused the file (415): %s", msg)
+	}
+	if msg == "" {
+		msg = strings.TrimSpace(string(body))
+	}
+	if msg == "" {
+		return fmt.Errorf("upload failed: %s", resp.Status)
+	}
+	return fmt.Errorf("upload failed: %s: %s", resp.Status, msg)
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
@@ -0,0 +1,173 @@
+// internal/images/manifest.go
+
+package images
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Record is one image, or media or file, uploaded to Ghost.
+type Record struct {
+	URL      string `json:"url"`
+	Kind     Kind   `json:"kind,omitempty"` // empty for images
+	File     string `json:"file"`           // where it was uploaded from
+	Size     int64  `json:"size"`
+	Uploaded string `json:"uploaded"` // RFC 3339
+
+	// What optimisation made of it: the settings, and the SHA1 and size
+	// of the bytes actually uploaded if they differ from the file's.
+	Options       string `json:"options,omitempty"`
+	Optimized     string `json:"optimized,omitempty"`
+	OptimizedSize int64  `json:"optimized_size,omitempty"`
+}
+
+const manifestVersion = 1
+
+var sumRe = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Manifest remembers every image uploaded to Ghost, keyed by the SHA1 of
+// its content, so the same bytes are never uploaded twice. It is safe for
+// concurrent use.
+type Manifest struct {
+	path string
+
+	// Dropped counts the entries LoadManifest threw away as invalid.
+	Dropped int `json:"-"`
+
+	mu      sync.Mutex
+	Version int               `json:"version"`
+	Site    string            `json:"site"` // API URL the images were uploaded to
+	Images  map[string]Record `json:"images"`
+
+	// Sources maps each mirrored remote image to the SHA1 of its content.
+	Sources map[string]string `json:"sources,omitempty"`
+}
+
+// LoadManifest reads the manifest at path, for uploads to site. A missing
+// file is empty. Entries that can't be used (a malformed key, a URL that
+// isn't absolute, or a different site altogether) are dropped, so the
+// images are uploaded again rather than linked to something broken.
+func LoadManifest(path, site string) (*Manifest, error) {
+	m := &Manifest{path: path, Images: map[string]Record{}}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		m.Version, m.Site = manifestVersion, site
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if m.Version > manifestVersion {
+		return nil, fmt.Errorf("%s: version %d is newer than this ghostpost understands", path, m.Version)
+	}
+	if m.Site != "" && m.Site != site {
+		m.Dropped, m.Images = len(m.Images), nil
+	}
+	m.Version, m.Site = manifestVersion, site
+	for sum, r := range m.Images {
+		if u, err := url.Parse(r.URL); !sumRe.MatchString(sum) || err != nil || !u.IsAbs() {
+			delete(m.Images, sum)
+			m.Dropped++
+		}
+	}
+	if m.Images == nil {
+		m.Images = map[string]Record{}
+	}
+	for src, sum := range m.Sources {
+		if _, ok := m.Images[sum]; !ok {
+			delete(m.Sources, src) // its upload is gone; mirror it again
+		}
+	}
+	return m, nil
+}
+
+// Get returns the record for content with the given SHA1.
+func (m *Manifest) Get(sum string) (Record, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.Images[sum]
+	return r, ok
+}
+
+// Put records an upload and saves.
+func (m *Manifest) Put(sum string, r Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Images[sum] = r
+	return m.save()
+}
+
+// Source returns the SHA1 of the content mirrored from the URL src.
+func (m *Manifest) Source(src string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sum, ok := m.Sources[src]
+	return sum, ok
+}
+
+// PutSource records that src was mirrored as the content sum and saves.
+func (m *Manifest) PutSource(src, sum string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Sources == nil {
+		m.Sources = map[string]string{}
+	}
+	m.Sources[src] = sum
+	return m.save()
+}
+
+// Delete forgets the given uploads and saves.
+func (m *Manifest) Delete(sums ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range sums {
+		delete(m.Images, s)
+	}
+	for src, sum := range m.Sources {
+		if _, ok := m.Images[sum]; !ok {
+			delete(m.Sources, src)
+		}
+	}
+	return m.save()
+}
+
+// Sums lists every recorded SHA1, sorted by file.
+func (m *Manifest) Sums() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]string, 0, len(m.Images))
+	for s := range m.Images {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return m.Images[out[i]].File < m.Images[out[j]].File
+	})
+	return out
+}
+
+// save writes the manifest atomically.
+func (m *Manifest) save() error {
+	buf, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o755); err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, append(buf, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
@@ -0,0 +1,172 @@
+// internal/images/mirror.go
+
+package images
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// maxMirrorSize caps how much of a remote image is downloaded.
+const maxMirrorSize = 50 << 20
+
+// Mirror decides which remote images are copied into Ghost's storage.
+// The zero value mirrors nothing.
+type Mirror struct {
+	Enabled bool
+	Allow   []string // only these domains (and their subdomains); empty means all
+	Deny    []string // never these, even if allowed
+}
+
+// Wants reports whether the remote image at ref should be mirrored.
+func (m Mirror) Wants(ref string) bool {
+	if !m.Enabled {
+		return false
+	}
+	u, err := url.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if matchDomain(host, m.Deny) {
+		return false
+	}
+	return len(m.Allow) == 0 || matchDomain(host, m.Allow)
+}
+
+func matchDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "."))
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Source is what to upload for the image reference ref in a post in dir:
+// a local file (see Resolve), a remote URL to mirror, or "" for nothing.
+func (s *Service) Source(ref, dir string) string {
+	if p := Resolve(ref, dir); p != "" {
+		return p
+	}
+	if s.Mirror.Wants(ref) && !s.onSite(ref) {
+		return ref
+	}
+	return ""
+}
+
+// onSite reports whether ref is already served by the Ghost site.
+func (s *Service) onSite(ref string) bool {
+	u, err := url.Parse(ref)
+	site, err2 := url.Parse(s.BaseURL)
+	return err == nil && err2 == nil && strings.EqualFold(u.Host, site.Host)
+}
+
+// mirror downloads the remote image at src and uploads it like a local
+// file, so content already in Ghost isn't uploaded again.
+func (s *Service) mirror(src string) (string, error) {
+	if url, ok := s.lookupSource(src); ok {
+		return url, nil
+	}
+	raw, name, err := s.download(src)
+	if err != nil {
+		return "", fmt.Errorf("mirror %s: %w", src, err)
+	}
+	sum := Sum(raw)
+	url, err := s.store(blob{name: name, file: src, size: int64(len(raw)), data: raw}, sum)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.sources[src] = sum
+	s.mu.Unlock()
+	if s.Manifest != nil {
+		if err := s.Manifest.PutSource(src, sum); err != nil {
+			return url, fmt.Errorf("mirrored, but could not update the image cache: %w", err)
+		}
+	}
+	return url, nil
+}
+
+// lookupSource finds an earlier mirror of the remote image src.
+func (s *Service) lookupSource(src string) (string, bool) {
+	s.mu.Lock()
+	sum, ok := s.sources[src]
+	s.mu.Unlock()
+	if !ok && s.Manifest != nil {
+		sum, ok = s.Manifest.Source(src)
+	}
+	if !ok {
+		return "", false
+	}
+	return s.lookup(sum)
+}
+
+// download fetches src and names it after the last element of its path,
+// with an extension for the type it turned out to be.
+func (s *Service) download(src string) ([]byte, string, error) {
+	resp, err := s.Client.Get(src)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, "", fmt.Errorf("%s", resp.Status)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxMirrorSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(raw) > maxMirrorSize {
+		return nil, "", fmt.Errorf("larger than %d MB", maxMirrorSize>>20)
+	}
+
+	ctype, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !isMedia(ctype) {
+		ctype = http.DetectContentType(raw)
+	}
+	if !isMedia(ctype) {
+		return nil, "", fmt.Errorf("not an image, audio or video (%s)", ctype)
+	}
+
+	u, _ := url.Parse(src)
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		name = "image"
+	}
+	if ext := extension(ctype); ext != "" && !matches(path.Ext(name), ctype) {
+		name = strings.TrimSuffix(name, path.Ext(name)) + ext
+	}
+	return raw, name, nil
+}
+
+// matches reports whether ext is an extension for the content type ctype.
+func matches(ext, ctype string) bool {
+	t, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext))
+	return ext != "" && t == ctype
+}
+
+func isMedia(ctype string) bool {
+	return strings.HasPrefix(ctype, "image/") || strings.HasPrefix(ctype, "audio/") ||
+		strings.HasPrefix(ctype, "video/")
+}
+
+// extension picks a file extension for a download of type ctype.
+func extension(ctype string) string {
+	switch ctype {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/svg+xml":
+		return ".svg"
+	}
+	if exts, _ := mime.ExtensionsByType(ctype); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
@@ -0,0 +1,234 @@
+// internal/images/mirror_test.go
+
+package images
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMirrorWants(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Mirror
+		ref  string
+		want bool
+	}{
+		{"disabled", Mirror{}, "https://cdn.example.com/a.png", false},
+		{"any domain", Mirror{Enabled: true}, "https://cdn.example.com/a.png", true},
+		{"plain http", Mirror{Enabled: true}, "http://cdn.example.com/a.png", true},
+		{"not http", Mirror{Enabled: true}, "ftp://cdn.example.com/a.png", false},
+		{"local path", Mirror{Enabled: true}, "images/a.png", false},
+		{"protocol-relative", Mirror{Enabled: true}, "//cdn.example.com/a.png", false},
+		{"allowed", Mirror{Enabled: true, Allow: []string{"example.com"}}, "https://example.com/a.png", true},
+		{"allowed subdomain", Mirror{Enabled: true, Allow: []string{"example.com"}}, "https://cdn.example.com/a.png", true},
+		{"allowed with dot", Mirror{Enabled: true, Allow: []string{".example.com"}}, "https://cdn.example.com/a.png", true},
+		{"not allowed", Mirror{Enabled: true, Allow: []string{"example.com"}}, "https://example.org/a.png", false},
+		{"suffix isn't a subdomain", Mirror{Enabled: true, Allow: []string{"example.com"}}, "https://badexample.com/a.png", false},
+		{"denied", Mirror{Enabled: true, Deny: []string{"tracker.example.com"}}, "https://tracker.example.com/a.png", false},
+		{"deny beats allow", Mirror{Enabled: true, Allow: []string{"example.com"}, Deny: []string{"tracker.example.com"}}, "https://px.tracker.example.com/a.png", false},
+		{"case-insensitive", Mirror{Enabled: true, Allow: []string{"Example.COM"}}, "https://CDN.example.com/a.png", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.Wants(tt.ref); got != tt.want {
+				t.Errorf("Wants(%q) = %v, want %v", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+// standIn plays a remote image host and Ghost's image upload endpoint,
+// counting downloads and uploads.
+type standIn struct {
+	remote, ghost      *httptest.Server
+	downloads, uploads atomic.Int32
+}
+
+func newStandIn(t *testing.T, files map[string][]byte) *standIn {
+	t.Helper()
+	s := &standIn{}
+	s.remote = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.downloads.Add(1)
+		raw, ok := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(raw)
+	}))
+	t.Cleanup(s.remote.Close)
+	s.ghost = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ghost/api/admin/images/upload/" {
+			http.NotFound(w, r)
+			return
+		}
+		s.uploads.Add(1)
+		if _, _, err := r.FormFile("file"); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"images": []map[string]string{{"url": s.ghost.URL + "/content/images/mirrored.png"}},
+		})
+	}))
+	t.Cleanup(s.ghost.Close)
+	return s
+}
+
+func (s *standIn) service(m *Manifest) *Service {
+	svc := New(s.ghost.URL+"/ghost/api/admin/", "jwt", http.DefaultClient)
+	svc.Mirror = Mirror{Enabled: true}
+	svc.Manifest = m
+	return svc
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewRGBA(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestMirrorUploadsOnceAcrossRuns(t *testing.T) {
+	srv := newStandIn(t, map[string][]byte{"a.png": testPNG(t)})
+	src := srv.remote.URL + "/a.png"
+	manifest := filepath.Join(t.TempDir(), "images.json")
+
+	m, err := LoadManifest(manifest, "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := srv.service(m)
+	if got := first.Source(src, "."); got != src {
+		t.Fatalf("Source = %q, want the URL to mirror", got)
+	}
+	url, err := first.Upload(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := srv.ghost.URL + "/content/images/mirrored.png"; url != want {
+		t.Errorf("Upload = %q, want %q", url, want)
+	}
+	if _, err := first.Upload(src); err != nil {
+		t.Fatal(err)
+	}
+	if d, u := srv.downloads.Load(), srv.uploads.Load(); d != 1 || u != 1 {
+		t.Errorf("same run: %d download(s), %d upload(s), want 1 and 1", d, u)
+	}
+
+	// A new run with the manifest on disk neither downloads nor uploads.
+	m, err = LoadManifest(manifest, "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := srv.service(m)
+	if cached, ok, _ := second.Cached(src); !ok || cached != url {
+		t.Errorf("Cached = %q, %v, want %q, true", cached, ok, url)
+	}
+	again, err := second.Upload(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != url {
+		t.Errorf("repeat run: Upload = %q, want %q", again, url)
+	}
+	if d, u := srv.downloads.Load(), srv.uploads.Load(); d != 1 || u != 1 {
+		t.Errorf("repeat run: %d download(s), %d upload(s) in all, want 1 and 1", d, u)
+	}
+}
+
+func TestMirrorSizeCap(t *testing.T) {
+	head := testPNG(t)
+	tests := []struct {
+		name    string
+		size    int
+		wantErr string
+	}{
+		{"at the cap", maxMirrorSize, ""},
+		{"over the cap", maxMirrorSize + 1, "larger than 50 MB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := make([]byte, tt.size)
+			copy(raw, head)
+			srv := newStandIn(t, map[string][]byte{"big.png": raw})
+			svc := srv.service(nil)
+
+			_, name, err := svc.download(srv.remote.URL + "/big.png")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("download: %v", err)
+				}
+				if name != "big.png" {
+					t.Errorf("name = %q, want big.png", name)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("download error = %v, want %q", err, tt.wantErr)
+			}
+			if _, err := svc.Upload(srv.remote.URL + "/big.png"); err == nil {
+				t.Error("Upload of an oversized image succeeded")
+			}
+			if n := srv.uploads.Load(); n != 0 {
+				t.Errorf("%d upload(s) of an oversized image", n)
+			}
+		})
+	}
+}
+
+func TestMirrorRefusesNonMedia(t *testing.T) {
+	srv := newStandIn(t, map[string][]byte{"page": []byte("<!doctype html><html></html>")})
+	_, _, err := srv.service(nil).download(srv.remote.URL + "/page")
+	if err == nil || !strings.Contains(err.Error(), "not an image") {
+		t.Fatalf("download error = %v, want not an image", err)
+	}
+}
+
+func TestMirrorSkipsSite(t *testing.T) {
+	srv := newStandIn(t, nil)
+	svc := srv.service(nil)
+	if got := svc.Source(srv.ghost.URL+"/content/images/a.png", "."); got != "" {
+		t.Errorf("Source of an image on the site = %q, want nothing", got)
+	}
+}
+
+func TestMirrorNamesByContent(t *testing.T) {
+	jpg := []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	tests := []struct {
+		path string
+		raw  []byte
+		want string
+	}{
+		{"/photo.png", testPNG(t), "photo.png"},
+		{"/photo", testPNG(t), "photo.png"},
+		{"/img.php", jpg, "img.jpg"},
+		{"/photo.jpeg", jpg, "photo.jpeg"},
+		{"/photo.png", jpg, "photo.jpg"},
+		{"/", testPNG(t), "image.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			srv := newStandIn(t, map[string][]byte{strings.TrimPrefix(tt.path, "/"): tt.raw})
+			_, name, err := srv.service(nil).download(srv.remote.URL + tt.path + "?id=3")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != tt.want {
+				t.Errorf("name = %q, want %q", name, tt.want)
+			}
+		})
+	}
+}
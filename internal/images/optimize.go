@@ -0,0 +1,362 @@
+// internal/images/optimize.go
+
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // for Dimensions
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // decode WebP sources too
+)
+
+// Options configure what happens to an image before upload. The zero value
+// uploads files exactly as they are.
+type Options struct {
+	MaxWidth      int  // scale wider images down to this width; 0 keeps the size
+	Quality       int  // re-encode JPEGs at this quality (1-100); 0 keeps them as they are
+	WebP          bool // convert to (lossless) WebP when that is smaller
+	StripMetadata bool // drop EXIF, GPS, XMP and comments
+}
+
+func (o Options) enabled() bool {
+	return o.MaxWidth > 0 || o.Quality > 0 || o.WebP || o.StripMetadata
+}
+
+// String identifies the settings in the image cache, so changing them
+// optimises and uploads images again.
+func (o Options) String() string {
+	if !o.enabled() {
+		return ""
+	}
+	return fmt.Sprintf("max_width=%d quality=%d webp=%t strip=%t", o.MaxWidth, o.Quality, o.WebP, o.StripMetadata)
+}
+
+// defaultQuality is used for JPEGs that must be re-encoded (to resize or
+// rotate them) when no quality is configured.
+const defaultQuality = 90
+
+// Optimize applies o to the image raw, named name, and returns the bytes to
+// upload and the name to upload them under. Anything that isn't a JPEG,
+// PNG or WebP, or that doesn't get smaller, is returned untouched.
+func Optimize(raw []byte, name string, o Options) ([]byte, string, error) {
+	if !o.enabled() {
+		return raw, name, nil
+	}
+	format := ""
+	switch http.DetectContentType(raw) {
+	case "image/jpeg":
+		format = "jpeg"
+	case "image/png":
+		format = "png"
+	case "image/webp":
+		format = "webp"
+	default:
+		return raw, name, nil // GIFs may be animated; SVGs aren't pixels
+	}
+
+	orientation := 1
+	if format == "jpeg" {
+		orientation = exifOrientation(raw)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", name, err)
+	}
+	width := cfg.Width
+	if orientation >= 5 { // stored sideways
+		width = cfg.Height
+	}
+	resize := o.MaxWidth > 0 && width > o.MaxWidth
+	reencode := resize || o.WebP || (format == "jpeg" && o.Quality > 0)
+	// Stripping or re-encoding loses the EXIF orientation tag, so rotated
+	// photos have to be turned the right way up first.
+	rotate := orientation != 1 && (o.StripMetadata || reencode)
+	reencode = reencode || rotate
+
+	if !reencode {
+		if o.StripMetadata {
+			raw = stripMetadata(raw, format)
+		}
+		return raw, name, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", name, err)
+	}
+	if rotate {
+		img = orient(img, orientation)
+	}
+	if resize {
+		b := img.Bounds()
+		h := max(b.Dy()*o.MaxWidth/b.Dx(), 1) // a very wide strip is still a pixel high
+		dst := image.NewNRGBA(image.Rect(0, 0, o.MaxWidth, h))
+		xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+		img = dst
+	}
+
+	var out bytes.Buffer
+	switch format {
+	case "jpeg":
+		q := o.Quality
+		if q == 0 {
+			q = defaultQuality
+		}
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: q})
+	case "png":
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&out, img)
+	case "webp":
+		err = nativewebp.Encode(&out, img, nil)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", name, err)
+	}
+	data, uploadName := out.Bytes(), name
+
+	if o.WebP && format != "webp" {
+		var w bytes.Buffer
+		if err := nativewebp.Encode(&w, img, nil); err == nil && w.Len() < len(data) {
+			data = w.Bytes()
+			uploadName = strings.TrimSuffix(name, filepath.Ext(name)) + ".webp"
+		}
+	}
+	// Re-encoding can make an already well-compressed file bigger; only
+	// resizing or rotating make that worth it.
+	if !resize && !rotate && len(data) >= len(raw) {
+		if o.StripMetadata {
+			raw = stripMetadata(raw, format)
+		}
+		return raw, name, nil
+	}
+	return data, uploadName, nil
+}
+
+// Dimensions reads the size the image at path is shown at: turned the right
+// way up, and scaled down to maxWidth as Optimize would. ok is false for
+// files whose size can't be read, such as SVGs.
+func Dimensions(path string, maxWidth int) (w, h int, ok bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, false
+	}
+	defer f.Close()
+	head := make([]byte, 256<<10) // room for the EXIF block before a JPEG's frame header
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(head))
+	if err != nil {
+		return 0, 0, false
+	}
+	w, h = cfg.Width, cfg.Height
+	if format == "jpeg" && exifOrientation(head) >= 5 {
+		w, h = h, w
+	}
+	if maxWidth > 0 && w > maxWidth && format != "gif" {
+		w, h = maxWidth, max(h*maxWidth/w, 1)
+	}
+	return w, h, true
+}
+
+// stripMetadata removes metadata without touching the pixels.
+func stripMetadata(raw []byte, format string) []byte {
+	switch format {
+	case "jpeg":
+		return stripJPEG(raw)
+	case "png":
+		return stripPNG(raw)
+	case "webp":
+		return stripWebP(raw)
+	}
+	return raw
+}
+
+// stripJPEG drops APPn segments (EXIF, XMP, Photoshop…) and comments, but
+// keeps JFIF (APP0), the ICC colour profile (APP2) and Adobe's colour
+// transform flag (APP14), which change how the image looks.
+func stripJPEG(raw []byte) []byte {
+	if len(raw) < 4 || raw[0] != 0xFF || raw[1] != 0xD8 {
+		return raw
+	}
+	out := []byte{0xFF, 0xD8}
+	for i := 2; i+4 <= len(raw); {
+		if raw[i] != 0xFF {
+			return raw // not where a marker should be; leave it alone
+		}
+		marker := raw[i+1]
+		if marker == 0xDA { // start of scan: the rest is image data
+			return append(out, raw[i:]...)
+		}
+		n := int(binary.BigEndian.Uint16(raw[i+2:]))
+		end := i + 2 + n
+		if end > len(raw) {
+			return raw
+		}
+		drop := marker == 0xFE || (marker >= 0xE1 && marker <= 0xEF && marker != 0xE2 && marker != 0xEE)
+		if !drop {
+			out = append(out, raw[i:end]...)
+		}
+		i = end
+	}
+	return raw
+}
+
+// stripPNG drops text, time and EXIF chunks.
+func stripPNG(raw []byte) []byte {
+	const sig = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(raw, []byte(sig)) {
+		return raw
+	}
+	out := []byte(sig)
+	for i := len(sig); i+12 <= len(raw); {
+		n := int(binary.BigEndian.Uint32(raw[i:]))
+		end := i + 12 + n
+		if end > len(raw) {
+			return raw
+		}
+		switch string(raw[i+4 : i+8]) {
+		case "tEXt", "zTXt", "iTXt", "tIME", "eXIf":
+		default:
+			out = append(out, raw[i:end]...)
+		}
+		i = end
+	}
+	return out
+}
+
+// stripWebP drops the EXIF and XMP chunks, and their flags in the VP8X
+// header, but keeps the ICC colour profile.
+func stripWebP(raw []byte) []byte {
+	if len(raw) < 12 || string(raw[:4]) != "RIFF" || string(raw[8:12]) != "WEBP" {
+		return raw
+	}
+	out := append([]byte(nil), raw[:12]...)
+	for i := 12; i < len(raw); {
+		if i+8 > len(raw) {
+			return raw
+		}
+		n := int(binary.LittleEndian.Uint32(raw[i+4:]))
+		end := i + 8 + n + n%2 // chunks are padded to an even size
+		if end > len(raw) {
+			if i+8+n != len(raw) {
+				return raw
+			}
+			end = len(raw) // a writer that left off the last pad byte
+		}
+		switch string(raw[i : i+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), raw[i:end]...)
+			if n > 0 {
+				chunk[8] &^= 0x08 | 0x04 // EXIF and XMP present
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, raw[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
+
+// exifOrientation reads the EXIF orientation tag (1-8) of a JPEG, or 1.
+func exifOrientation(raw []byte) int {
+	for i := 2; i+4 <= len(raw) && raw[i] == 0xFF; {
+		marker := raw[i+1]
+		n := int(binary.BigEndian.Uint16(raw[i+2:]))
+		if marker == 0xDA || i+2+n > len(raw) {
+			break
+		}
+		seg := raw[i+4 : i+2+n]
+		if marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return tiffOrientation(seg[6:])
+		}
+		i += 2 + n
+	}
+	return 1
+}
+
+func tiffOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 1
+	}
+	var bo binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(bo.Uint32(t[4:]))
+	if ifd+2 > len(t) {
+		return 1
+	}
+	count := int(bo.Uint16(t[ifd:]))
+	for e := 0; e < count; e++ {
+		p := ifd + 2 + e*12
+		if p+12 > len(t) {
+			break
+		}
+		if bo.Uint16(t[p:]) == 0x0112 {
+			if v := int(bo.Uint16(t[p+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+		}
+	}
+	return 1
+}
+
+// orient turns img the right way up for EXIF orientation o.
+func orient(img image.Image, o int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	src := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			default:
+				dx, dy = x, y
+			}
+			dst.SetNRGBA(dx, dy, src.NRGBAAt(x, y))
+		}
+	}
+	return dst
+}
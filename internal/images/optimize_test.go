@@ -0,0 +1,90 @@
+// internal/images/optimize_test.go
+
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+func riffChunk(fourCC string, payload []byte) []byte {
+	c := append([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(payload)))...)
+	c = append(c, payload...)
+	if len(payload)%2 == 1 {
+		c = append(c, 0)
+	}
+	return c
+}
+
+// extendedWebP wraps a lossless WebP of img in the extended format, with
+// the given chunks after the image data.
+func extendedWebP(t *testing.T, img image.Image, flags byte, extra ...[]byte) []byte {
+	t.Helper()
+	var simple bytes.Buffer
+	if err := nativewebp.Encode(&simple, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	b := img.Bounds()
+	vp8x := []byte{flags, 0, 0, 0}
+	vp8x = append(vp8x, byte(b.Dx()-1), byte((b.Dx()-1)>>8), byte((b.Dx()-1)>>16))
+	vp8x = append(vp8x, byte(b.Dy()-1), byte((b.Dy()-1)>>8), byte((b.Dy()-1)>>16))
+
+	body := []byte("WEBP")
+	body = append(body, riffChunk("VP8X", vp8x)...)
+	body = append(body, simple.Bytes()[12:]...) // the VP8L chunk
+	for _, c := range extra {
+		body = append(body, c...)
+	}
+	return append(append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...), body...)
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	exif := riffChunk("EXIF", []byte("Exif\x00\x00GPS here"))
+	xmp := riffChunk("XMP ", []byte("<x:xmpmeta/>"))
+	raw := extendedWebP(t, img, 0x08|0x04, exif, xmp)
+
+	out, name, err := Optimize(raw, "a.webp", Options{StripMetadata: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "a.webp" {
+		t.Errorf("name = %q, want a.webp", name)
+	}
+	if bytes.Contains(out, []byte("EXIF")) || bytes.Contains(out, []byte("XMP ")) || bytes.Contains(out, []byte("GPS here")) {
+		t.Errorf("metadata left in %q", out)
+	}
+	if got := binary.LittleEndian.Uint32(out[4:]); int(got) != len(out)-8 {
+		t.Errorf("RIFF size = %d, want %d", got, len(out)-8)
+	}
+	if flags := out[20]; flags&(0x08|0x04) != 0 {
+		t.Errorf("VP8X flags = %#x, want EXIF and XMP cleared", flags)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(out))
+	if err != nil || format != "webp" || cfg.Width != 3 || cfg.Height != 2 {
+		t.Errorf("stripped file decodes as %s %dx%d, %v; want webp 3x2", format, cfg.Width, cfg.Height, err)
+	}
+}
+
+func TestResizeVeryWideImage(t *testing.T) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewNRGBA(image.Rect(0, 0, 1000, 1))); err != nil {
+		t.Fatal(err)
+	}
+	out, _, err := Optimize(b.Bytes(), "strip.png", Options{MaxWidth: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 100 || cfg.Height != 1 {
+		t.Errorf("resized to %dx%d, want 100x1", cfg.Width, cfg.Height)
+	}
+}
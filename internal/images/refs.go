@@ -0,0 +1,220 @@
+// internal/images/refs.go
+
+package images
+
+import (
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// Ref is one image, or linked attachment, a post refers to.
+type Ref struct {
+	Dest string // as written: a Markdown destination, an <img src> or <a href>
+	Path string // the local file it names, or "" for a remote image
+	Link bool   // a link to a local file rather than an image
+}
+
+var mdParser = goldmark.New().Parser()
+
+// refAttrRe finds the src of <img>, <audio>, <video> and <source> tags, and
+// the href of <a> tags, in raw HTML.
+var refAttrRe = regexp.MustCompile(`(?i)(<(?:img|audio|video|source)\b[^>]*?\bsrc\s*=\s*|<a\b[^>]*?\bhref\s*=\s*)("[^"]*"|'[^']*'|[^\s"'>]+)`)
+
+// Refs lists every image md refers to, in order: inline and reference-style
+// Markdown images, and <img> tags in raw HTML. Local paths are resolved
+// against dir (see Resolve). Links (Markdown or <a>) to local files of a
+// type l allows, such as audio or PDFs, are included as attachments.
+func Refs(md []byte, dir string, l Limits) []Ref {
+	var out []Ref
+	walkRefs(mdParser.Parse(text.NewReader(md)), md, func(dest string, link bool) string {
+		if !link {
+			out = append(out, Ref{Dest: dest, Path: Resolve(dest, dir)})
+		} else if path := Attachment(dest, dir, l); path != "" {
+			out = append(out, Ref{Dest: dest, Path: path, Link: true})
+		}
+		return dest
+	})
+	return out
+}
+
+// Attachment finds the local file a link names. Unlike an image, the file
+// must exist and be a type l allows: a link to a missing file, a directory,
+// another post, a page on the site or, say, a shell script is just a link.
+func Attachment(dest, dir string, l Limits) string {
+	path := Resolve(dest, dir)
+	if path == "" || !l.allows(path) {
+		return ""
+	}
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return ""
+	}
+	return path
+}
+
+// Resolve finds the file a local image reference names. Relative paths are
+// relative to dir; paths starting with / are relative to the repository
+// root, and are taken for site URLs (left alone) if no such file exists.
+// URL-encoded paths (my%20photo.png) are decoded. Remote URLs, and paths
+// outside the repository, give "".
+func Resolve(dest, dir string) string {
+	if !IsLocal(dest) {
+		return ""
+	}
+	root := RepoRoot(dir)
+	candidates := []string{dest}
+	if un, err := url.PathUnescape(dest); err == nil && un != dest {
+		candidates = []string{un, dest}
+	}
+	for _, c := range candidates {
+		p := filepath.Join(dir, filepath.FromSlash(c))
+		if strings.HasPrefix(c, "/") {
+			p = filepath.Join(root, filepath.FromSlash(c))
+		}
+		if _, err := os.Stat(p); err == nil {
+			if !within(root, p) {
+				return ""
+			}
+			return p
+		}
+	}
+	if strings.HasPrefix(dest, "/") {
+		return ""
+	}
+	p := filepath.Join(dir, filepath.FromSlash(candidates[0])) // missing, but still local
+	if !within(root, p) {
+		return ""
+	}
+	return p
+}
+
+// within reports whether path is inside root once symlinks are followed,
+// so a post can't publish a file from elsewhere on the machine.
+func within(root, path string) bool {
+	abs := func(p string) string {
+		a, err := filepath.Abs(p)
+		if err != nil {
+			return p
+		}
+		if r, err := filepath.EvalSymlinks(a); err == nil {
+			return r
+		}
+		return a
+	}
+	rel, err := filepath.Rel(abs(root), abs(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// RepoRoot is the nearest directory above dir holding .git, or the
+// working directory if there is none.
+func RepoRoot(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "."
+	}
+	for d := abs; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		if filepath.Dir(d) == d {
+			return "."
+		}
+	}
+}
+
+// Rewriter is a goldmark AST transformer that points image references at
+// their uploaded URLs, keyed by Ref.Dest. References it doesn't know keep
+// their destination.
+type Rewriter map[string]string
+
+func (r Rewriter) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	walkRefs(doc, reader.Source(), func(dest string, _ bool) string {
+		if u, ok := r[dest]; ok {
+			return u
+		}
+		return dest
+	})
+}
+
+// walkRefs calls fn for every image and link under doc, telling them apart
+// by link, and replaces the destination with what fn returns.
+func walkRefs(doc ast.Node, source []byte, fn func(dest string, link bool) string) {
+	type swap struct{ old, new ast.Node }
+	var swaps []swap
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Image:
+			n.Destination = []byte(fn(string(n.Destination), false))
+		case *ast.Link:
+			n.Destination = []byte(fn(string(n.Destination), true))
+		case *ast.RawHTML:
+			raw := segmentsText(n.Segments, source)
+			if out := rewriteHTML(raw, fn); out != raw {
+				swaps = append(swaps, swap{n, rawString(out)})
+			}
+		case *ast.HTMLBlock:
+			raw := segmentsText(n.Lines(), source)
+			if n.HasClosure() {
+				raw += string(n.ClosureLine.Value(source))
+			}
+			if out := rewriteHTML(raw, fn); out != raw {
+				swaps = append(swaps, swap{n, rawString(out)})
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, s := range swaps {
+		s.old.Parent().ReplaceChild(s.old.Parent(), s.old, s.new)
+	}
+}
+
+func segmentsText(segs *text.Segments, source []byte) string {
+	var b strings.Builder
+	for i := 0; i < segs.Len(); i++ {
+		s := segs.At(i)
+		b.Write(s.Value(source))
+	}
+	return b.String()
+}
+
+// rawString is HTML written out as-is.
+func rawString(s string) ast.Node {
+	n := ast.NewString([]byte(s))
+	n.SetCode(true)
+	return n
+}
+
+// rewriteHTML calls fn for every src and href refAttrRe finds in raw.
+func rewriteHTML(raw string, fn func(dest string, link bool) string) string {
+	return refAttrRe.ReplaceAllStringFunc(raw, func(m string) string {
+		sub := refAttrRe.FindStringSubmatch(m)
+		link := strings.HasPrefix(strings.ToLower(sub[1]), "<a")
+		value := sub[2]
+		quote := ""
+		if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+			quote, value = value[:1], value[1:len(value)-1]
+		}
+		dest := html.UnescapeString(value)
+		if out := fn(dest, link); out != dest {
+			if quote == "" {
+				quote = `"`
+			}
+			return sub[1] + quote + html.EscapeString(out) + quote
+		}
+		return m
+	})
+}
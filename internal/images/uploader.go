@@ -6,24 +6,39 @@ import (
 	"bytes"
 	"crypto/sha1"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
 	"os"
 	"path/filepath"
-	"regexp"
+	"strings"
+	"sync"
+	"time"
 )
 
-var imgRe = regexp.MustCompile(`!\[[^\]]*]\(([^)]+)\)`)
-
 type Service struct {
 	BaseURL  string
 	Client   *http.Client
 	AdminJWT string
-	cache    map[string]string // sha1 → remoteURL
+	Manifest *Manifest // optional; remembers uploads across runs
+	Optimize Options   // applied before upload
+	Mirror   Mirror    // remote images to copy into Ghost
+	Limits   Limits    // checked before each upload
+	mu       sync.Mutex
+	cache    map[string]string  // sha1 → remoteURL
+	inflight map[string]*upload // sha1 → upload in progress
+	sources  map[string]string  // mirrored URL → sha1
+}
+
+// upload is one file being sent to Ghost. Others wanting the same content
+// wait for it instead of uploading it again.
+type upload struct {
+	done chan struct{}
+	url  string
+	err  error
 }
 
 func New(base string, jwt string, c *http.Client) *Service {
@@ -32,73 +47,349 @@ func New(base string, jwt string, c *http.Client) *Service {
 		Client:   c,
 		AdminJWT: jwt,
 		cache:    make(map[string]string),
+		inflight: make(map[string]*upload),
+		sources:  make(map[string]string),
 	}
 }
 
-func (s *Service) Rewrite(md []byte, root string) ([]byte, error) {
-	return imgRe.ReplaceAllFunc(md, func(m []byte) []byte {
-		match := imgRe.FindSubmatch(m)
-		locPath := string(match[1])
-		full := filepath.Join(root, locPath)
-
-		remote, err := s.upload(full)
+// UploadAll uploads every local image md refers to, and mirrors the remote
+// ones s.Mirror wants, and returns their URLs, keyed by Ref.Dest, for
+// Rewriter. Every image is tried; the ones that fail to upload are
+// returned as errors, joined.
+func (s *Service) UploadAll(md []byte, dir string) (map[string]string, error) {
+	urls := map[string]string{}
+	var errs []error
+	for _, r := range Refs(md, dir, s.Limits) {
+		src := s.Source(r.Dest, dir)
+		if src == "" {
+			continue
+		}
+		if _, done := urls[r.Dest]; done {
+			continue
+		}
+		remote, err := s.Upload(src)
 		if err != nil {
-			fmt.Printf("Error uploading file: %s\n", err.Error())
-			return m // keep local ref if upload fails
+			errs = append(errs, fmt.Errorf("uploading %s: %w", r.Dest, err))
+			continue
+		}
+		urls[r.Dest] = remote
+	}
+	return urls, errors.Join(errs...)
+}
+
+// Lookup finds the images referenced in md without uploading anything.
+// Images already in the cache are returned in uploaded (ref → URL); the rest
+// are returned in pending (ref → absolute path, or the URL to mirror), ready
+// for Upload. Unreadable files are left out of both.
+func (s *Service) Lookup(md []byte, dir string) (uploaded, pending map[string]string) {
+	uploaded = map[string]string{}
+	pending = map[string]string{}
+	for _, r := range Refs(md, dir, s.Limits) {
+		s.LookupRef(r.Dest, dir, uploaded, pending)
+	}
+	return uploaded, pending
+}
+
+// LookupRef is Lookup for a single reference, such as a feature image.
+// Remote URLs are ignored unless they are to be mirrored.
+func (s *Service) LookupRef(ref, dir string, uploaded, pending map[string]string) {
+	path := s.Source(ref, dir)
+	if path == "" {
+		return
+	}
+	if !IsLocal(path) {
+		if url, ok := s.lookupSource(path); ok {
+			uploaded[ref] = url
+		} else {
+			pending[ref] = path
 		}
-		return bytes.Replace(m, match[1], []byte(remote), 1)
-	}), nil
+		return
+	}
+	full, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	sum, _, err := hashFile(full)
+	if err != nil {
+		return
+	}
+	if url, ok := s.lookup(sum); ok {
+		uploaded[ref] = url
+	} else {
+		pending[ref] = full
+	}
+}
+
+// Sum is the content key uploads are cached under.
+func Sum(raw []byte) string {
+	return fmt.Sprintf("%x", sha1.Sum(raw))
 }
 
-func (s *Service) upload(path string) (string, error) {
-	raw, err := os.ReadFile(path)
+// hashFile is Sum for the file at path, without reading it into memory,
+// and its size.
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha1.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), n, nil
+}
+
+// lookup finds an earlier upload of the content with the given sum.
+func (s *Service) lookup(sum string) (string, bool) {
+	s.mu.Lock()
+	url, ok := s.cache[sum]
+	s.mu.Unlock()
+	if ok {
+		return url, true
+	}
+	if s.Manifest != nil {
+		// an upload made with other settings doesn't count
+		if r, ok := s.Manifest.Get(sum); ok && r.Options == s.options(r.Kind).String() {
+			return r.URL, true
+		}
+	}
+	return "", false
+}
+
+// Cached reports whether the file at path, or the remote image to mirror,
+// has been uploaded before, and where to.
+func (s *Service) Cached(path string) (string, bool, error) {
+	if !IsLocal(path) {
+		url, ok := s.lookupSource(path)
+		return url, ok, nil
+	}
+	sum, _, err := hashFile(path)
+	if err != nil {
+		return "", false, err
+	}
+	url, ok := s.lookup(sum)
+	return url, ok, nil
+}
+
+// IsLocal reports whether ref points at a file on disk rather than a URL.
+func IsLocal(ref string) bool {
+	return ref != "" && !strings.Contains(ref, "://") &&
+		!strings.HasPrefix(ref, "//") && !strings.HasPrefix(ref, "data:")
+}
+
+// Upload sends one local file to Ghost, or mirrors a remote image (see
+// Source), reusing the URL of an earlier upload with identical content.
+func (s *Service) Upload(path string) (string, error) {
+	if !IsLocal(path) {
+		return s.mirror(path)
+	}
+	sum, size, err := hashFile(path)
 	if err != nil {
 		return "", err
 	}
-	sum := fmt.Sprintf("%x", sha1.Sum(raw))
+	return s.store(blob{name: filepath.Base(path), file: displayPath(path), size: size, path: path}, sum)
+}
+
+// blob is the content of one upload: a file on disk, streamed from there,
+// or bytes already in memory (a download, or an optimised image).
+type blob struct {
+	name string // uploaded under this name
+	file string // where it came from, for the manifest
+	size int64
+	path string // read from here if set…
+	data []byte // …else from here
+}
+
+func (b blob) open() (io.ReadCloser, error) {
+	if b.path != "" {
+		return os.Open(b.path)
+	}
+	return io.NopCloser(bytes.NewReader(b.data)), nil
+}
+
+func (b blob) bytes() ([]byte, error) {
+	if b.path != "" {
+		return os.ReadFile(b.path)
+	}
+	return b.data, nil
+}
+
+// head returns the first bytes of b, enough to sniff its type.
+func (b blob) head() ([]byte, error) {
+	r, err := b.open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return buf[:n], err
+}
+
+// store uploads b unless content with the same sum is already in Ghost.
+// Concurrent uploads of the same content share one request.
+func (s *Service) store(b blob, sum string) (string, error) {
+	if url, ok := s.lookup(sum); ok {
+		return url, nil
+	}
+
+	s.mu.Lock()
 	if url, ok := s.cache[sum]; ok {
+		s.mu.Unlock()
 		return url, nil
 	}
+	if u, ok := s.inflight[sum]; ok {
+		s.mu.Unlock()
+		<-u.done
+		return u.url, u.err
+	}
+	u := &upload{done: make(chan struct{})}
+	s.inflight[sum] = u
+	s.mu.Unlock()
+
+	u.url, u.err = s.send(b, sum)
+	s.mu.Lock()
+	delete(s.inflight, sum)
+	s.mu.Unlock()
+	close(u.done)
+	return u.url, u.err
+}
+
+// options are the optimisation settings for uploads of kind k: only images
+// are optimised.
+func (s *Service) options(k Kind) Options {
+	if k == "" || k == KindImage {
+		return s.Optimize
+	}
+	return Options{}
+}
 
-	body := &bytes.Buffer{}
-	w, err := imageFormWriter(raw, body, path)
+// send checks b against s.Limits, streams it to the endpoint for its kind
+// and records the URL in the cache and manifest. Images are optimised
+// first, in memory.
+func (s *Service) send(b blob, sum string) (string, error) {
+	kind := KindOf(b.name)
+	opts := s.options(kind)
+	up, optimized := b, false
+	if opts.enabled() {
+		raw, err := b.bytes()
+		if err != nil {
+			return "", err
+		}
+		data, name, err := Optimize(raw, b.name, opts)
+		if err != nil {
+			return "", err
+		}
+		if !bytes.Equal(data, raw) {
+			up, optimized = blob{name: name, file: b.file, size: int64(len(data)), data: data}, true
+		}
+	}
+	head, err := up.head()
+	if err != nil {
+		return "", err
+	}
+	ctype, err := s.Limits.check(kind, up.name, up.size, head)
 	if err != nil {
 		return "", err
 	}
-	w.Close()
 
-	req, _ := http.NewRequest("POST", s.BaseURL+"images/upload/", body)
+	// The form is written into a pipe as the request reads it, so the file
+	// is never held in memory. GetBody starts over, to follow redirects.
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	body := func() (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+		w := multipart.NewWriter(pw)
+		w.SetBoundary(boundary)
+		go func() { pw.CloseWithError(writeForm(w, up, ctype)) }()
+		return pr, nil
+	}
+	pr, _ := body()
+	req, err := http.NewRequest("POST", s.BaseURL+string(kind)+"/upload/", pr)
+	if err != nil {
+		pr.Close()
+		return "", err
+	}
+	req.GetBody = body
 	req.Header.Set("Authorization", "Ghost "+s.AdminJWT)
-	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
 	resp, err := s.Client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("upload failed %v", err)
+		return "", fmt.Errorf("upload failed: %w", err)
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
-		return "", fmt.Errorf("status code %d", resp.StatusCode)
+		return "", uploadError(resp, kind, up.size)
 	}
-	// parse {"images":[{"url":"https://…"}]}
-	var r struct {
-		Images []struct {
-			URL string `json:"url"`
-		}
+	// parse {"images":[{"url":"https://…"}]}, or "media" or "files"
+	var r map[string][]struct {
+		URL string `json:"url"`
 	}
 	json.NewDecoder(resp.Body).Decode(&r)
-	remote := r.Images[0].URL
+	if len(r[string(kind)]) == 0 {
+		return "", fmt.Errorf("no %s returned", kind)
+	}
+	remote := r[string(kind)][0].URL
+	s.mu.Lock()
 	s.cache[sum] = remote
+	s.mu.Unlock()
+	if s.Manifest != nil {
+		r := Record{
+			URL:      remote,
+			File:     b.file,
+			Size:     b.size,
+			Uploaded: time.Now().UTC().Format(time.RFC3339),
+			Options:  opts.String(),
+		}
+		if kind != KindImage {
+			r.Kind = kind
+		}
+		if optimized {
+			r.Optimized, r.OptimizedSize = Sum(up.data), up.size
+		}
+		err := s.Manifest.Put(sum, r)
+		if err != nil {
+			return remote, fmt.Errorf("uploaded, but could not update the image cache: %w", err)
+		}
+	}
 	return remote, nil
 }
 
-func imageFormWriter(file []byte, body *bytes.Buffer, path string) (*multipart.Writer, error) {
-	w := multipart.NewWriter(body)
+// displayPath makes path relative to the working directory when it can.
+func displayPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, abs); err == nil && !strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// writeForm writes b as the multipart form Ghost's upload endpoints take.
+func writeForm(w *multipart.Writer, b blob, ctype string) error {
 	h := make(textproto.MIMEHeader)
-	h.Set("Content-Disposition", multipart.FileContentDisposition("file", filepath.Base(path)))
-	h.Set("Content-Type", mime.TypeByExtension(filepath.Ext(path)))
-	part, _ := w.CreatePart(h)
-	_, err := io.Copy(part, bytes.NewReader(file))
+	h.Set("Content-Disposition", multipart.FileContentDisposition("file", b.name))
+	h.Set("Content-Type", ctype)
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	r, err := b.open()
 	if err != nil {
-		return nil, fmt.Errorf("error writing form: %s", err.Error())
+		return err
+	}
+	defer r.Close()
+	if _, err := io.Copy(part, r); err != nil {
+		return fmt.Errorf("error writing form: %w", err)
+	}
+	if err := w.WriteField("ref", b.name); err != nil {
+		return err
 	}
-	w.WriteField("ref", path)
-	return w, nil
+	return w.Close()
 }
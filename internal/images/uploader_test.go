@@ -0,0 +1,41 @@
+// internal/images/uploader_test.go
+
+package images
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUploadAllReturnsFailures(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil { // the repository root
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ok.png"), testPNG(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fake.png"), []byte("<!doctype html><html></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := newStandIn(t, nil)
+	svc := srv.service(nil)
+
+	urls, err := svc.UploadAll([]byte("![ok](ok.png) ![fake](fake.png) ![gone](gone.png)\n"), dir)
+	if err == nil {
+		t.Fatal("UploadAll succeeded with a fake and a missing image")
+	}
+	for _, want := range []string{"uploading fake.png:", "uploading gone.png:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't mention %q", err, want)
+		}
+	}
+	if _, ok := urls["ok.png"]; !ok || len(urls) != 1 {
+		t.Errorf("urls = %v, want only ok.png", urls)
+	}
+	if n := srv.uploads.Load(); n != 1 {
+		t.Errorf("%d upload(s), want 1", n)
+	}
+}
@@ -0,0 +1,147 @@
+// internal/render/cards.go
+
+package render
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+)
+
+// cards turns top-level paragraphs holding nothing but one image or link,
+// or nothing but images, into Ghost cards.
+type cards struct{ o Options }
+
+func (t *cards) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	source := reader.Source()
+	var swaps [][2]ast.Node
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		p, ok := n.(*ast.Paragraph)
+		if !ok {
+			continue
+		}
+		if gs := t.galleryCards(p, source); gs != nil {
+			// a long run of images makes several galleries in a row
+			for i := len(gs) - 1; i > 0; i-- {
+				doc.InsertAfter(doc, p, gs[i])
+			}
+			swaps = append(swaps, [2]ast.Node{p, gs[0]})
+			continue
+		}
+		only, attrs, ok := lone(p, source)
+		if !ok {
+			continue
+		}
+		var card ast.Node
+		if attrs == nil {
+			card = t.mediaCard(only, source)
+		}
+		if card == nil {
+			card = t.imageCard(only, attrs, source)
+		}
+		if card != nil {
+			swaps = append(swaps, [2]ast.Node{p, card})
+		}
+	}
+	for _, s := range swaps {
+		doc.ReplaceChild(doc, s[0], s[1])
+	}
+}
+
+// attrBlockRe matches an attribute block such as {.wide caption="…"}.
+var attrBlockRe = regexp.MustCompile(`^\s*\{([^{}]*)\}\s*$`)
+
+// lone finds the only node in p, ignoring blank text, and the attribute
+// block that follows it, if any.
+func lone(p *ast.Paragraph, source []byte) (ast.Node, map[string]string, bool) {
+	only := p.FirstChild()
+	for only != nil && isBlank(only, source) {
+		only = only.NextSibling()
+	}
+	if only == nil {
+		return nil, nil, false
+	}
+	var rest strings.Builder
+	for c := only.NextSibling(); c != nil; c = c.NextSibling() {
+		t, ok := c.(*ast.Text)
+		if !ok {
+			return nil, nil, false
+		}
+		rest.Write(t.Value(source))
+	}
+	if strings.TrimSpace(rest.String()) == "" {
+		return only, nil, true
+	}
+	m := attrBlockRe.FindStringSubmatch(rest.String())
+	if m == nil {
+		return nil, nil, false
+	}
+	return only, parseAttrs(m[1]), true
+}
+
+func isBlank(n ast.Node, source []byte) bool {
+	t, ok := n.(*ast.Text)
+	return ok && strings.TrimSpace(string(t.Value(source))) == ""
+}
+
+var attrRe = regexp.MustCompile(`\.([\w-]+)|([\w-]+)=("[^"]*"|'[^']*'|[^\s"']+)`)
+
+// parseAttrs reads `.class` and `key=value` pairs; classes are returned as
+// "class" keys ({.wide} is class=wide).
+func parseAttrs(s string) map[string]string {
+	out := map[string]string{}
+	for _, m := range attrRe.FindAllStringSubmatch(s, -1) {
+		if m[1] != "" {
+			out["class"] = strings.TrimSpace(out["class"] + " " + m[1])
+			continue
+		}
+		v := m[3]
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') {
+			v = v[1 : len(v)-1]
+		}
+		out[m[2]] = v
+	}
+	return out
+}
+
+// plainText is the text under n, without markup.
+func plainText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			switch c := c.(type) {
+			case *ast.Text:
+				b.Write(c.Value(source))
+			case *ast.String:
+				b.WriteString(stringValue(c))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// stringValue is the text of s. Code strings, such as the typographer's
+// curly quotes, are HTML entities.
+func stringValue(s *ast.String) string {
+	if s.IsCode() {
+		return html.UnescapeString(string(s.Value))
+	}
+	return string(s.Value)
+}
+
+// cardRenderer writes cards as the HTML Ghost renders for them, which is
+// also what it recognises when converting HTML into its editor's cards.
+type cardRenderer struct{}
+
+func (cardRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindMediaCard, renderMediaCard)
+	reg.Register(kindImageCard, renderImageCard)
+	reg.Register(kindGalleryCard, renderGalleryCard)
+	reg.Register(kindShortcode, renderShortcode)
+}
@@ -0,0 +1,77 @@
+// internal/render/extensions.go
+
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+)
+
+// Extensions switches Markdown extensions on or off by name. Names left out
+// keep their default (see DefaultExtensions).
+type Extensions map[string]bool
+
+// extensions are the Markdown extensions there are, by name.
+var extensions = map[string]goldmark.Option{
+	"gfm":              goldmark.WithExtensions(extension.GFM), // tables, strikethrough, task lists, bare URLs
+	"footnotes":        goldmark.WithExtensions(extension.Footnote),
+	"definition_lists": goldmark.WithExtensions(extension.DefinitionList),
+	"typographer":      goldmark.WithExtensions(extension.Typographer), // smart quotes, dashes, ellipses
+	"heading_ids":      goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	"attributes":       goldmark.WithParserOptions(parser.WithAttribute()), // ## Heading {#id .class}
+}
+
+// DefaultExtensions are on unless switched off. There are none, so a post
+// renders as it always has until its site or front-matter opts in.
+var DefaultExtensions = Extensions{}
+
+// With is e with the names in o switched as o says.
+func (e Extensions) With(o Extensions) Extensions {
+	out := Extensions{}
+	for k, v := range e {
+		out[k] = v
+	}
+	for k, v := range o {
+		out[k] = v
+	}
+	return out
+}
+
+// Check reports a name that isn't an extension.
+func (e Extensions) Check() error {
+	for k := range e {
+		if _, ok := extensions[k]; !ok {
+			names := make([]string, 0, len(extensions))
+			for name := range extensions {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unknown Markdown extension %q (want %s)", k, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// options are the goldmark options for the extensions that are on.
+func (e Extensions) options() []goldmark.Option {
+	all := DefaultExtensions.With(e)
+	names := make([]string, 0, len(all))
+	for k, on := range all {
+		if on {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	var out []goldmark.Option
+	for _, k := range names {
+		if opt, ok := extensions[k]; ok {
+			out = append(out, opt)
+		}
+	}
+	return out
+}
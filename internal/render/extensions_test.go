@@ -0,0 +1,95 @@
+// internal/render/extensions_test.go
+
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtensions(t *testing.T) {
+	tests := []struct {
+		name, md string
+		ext      Extensions
+		want     string
+	}{
+		{"all off by default", "~~gone~~ and https://e.com\n", nil, "<p>~~gone~~ and https://e.com</p>\n"},
+		{"gfm table", "| a |\n|---|\n| 1 |\n", Extensions{"gfm": true}, "<table>\n<thead>\n<tr>\n<th>a</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n</tr>\n</tbody>\n</table>\n"},
+		{
+			"gfm strikethrough, bare URLs and task lists",
+			"~~gone~~ and https://e.com\n\n- [x] done\n", Extensions{"gfm": true},
+			"<p><del>gone</del> and <a href=\"https://e.com\">https://e.com</a></p>\n<ul>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"> done</li>\n</ul>\n",
+		},
+		{"switched off again", "~~gone~~\n", Extensions{"gfm": false}, "<p>~~gone~~</p>\n"},
+		{
+			"footnotes",
+			"Note[^1].\n\n[^1]: The note.\n", Extensions{"footnotes": true},
+			"<p>Note<sup id=\"fnref:1\"><a href=\"#fn:1\" class=\"footnote-ref\" role=\"doc-noteref\">1</a></sup>.</p>\n" +
+				"<div class=\"footnotes\" role=\"doc-endnotes\">\n<hr>\n<ol>\n<li id=\"fn:1\">\n" +
+				"<p>The note.&#160;<a href=\"#fnref:1\" class=\"footnote-backref\" role=\"doc-backlink\">&#x21a9;&#xfe0e;</a></p>\n</li>\n</ol>\n</div>\n",
+		},
+		{"no footnotes", "Note[^1].\n\n[^1]: The note.\n", nil, "<p>Note[^1].</p>\n<p>[^1]: The note.</p>\n"},
+		{"definition lists", "Term\n: Definition\n", Extensions{"definition_lists": true}, "<dl>\n<dt>Term</dt>\n<dd>Definition</dd>\n</dl>\n"},
+		{"typographer", "\"Quote\" -- dash... 'it'\n", Extensions{"typographer": true}, "<p>&ldquo;Quote&rdquo; &ndash; dash&hellip; &lsquo;it&rsquo;</p>\n"},
+		{"no typographer", "\"Quote\" -- dash...\n", nil, "<p>&quot;Quote&quot; -- dash...</p>\n"},
+		{"heading IDs", "## Hello World\n", Extensions{"heading_ids": true}, "<h2 id=\"hello-world\">Hello World</h2>\n"},
+		{"attributes", "## Hello {#custom .big}\n", Extensions{"attributes": true}, "<h2 id=\"custom\" class=\"big\">Hello</h2>\n"},
+		{"no attributes", "## Hello {#custom .big}\n", nil, "<h2>Hello {#custom .big}</h2>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HTML([]byte(tt.md), Options{Extensions: tt.ext})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("HTML(%q) with %v\n got %q\nwant %q", tt.md, tt.ext, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtensionsLexical(t *testing.T) {
+	lexical, err := New("lexical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := lexical.Render([]byte("~~gone~~\n"), Options{Extensions: Extensions{"gfm": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.Lexical, lexText("gone", formatStrikethrough)) {
+		t.Errorf("strikethrough is not a text format:\n%s", out.Lexical)
+	}
+	out, err = lexical.Render([]byte("| a |\n|---|\n| 1 |\n"), Options{Extensions: Extensions{"gfm": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.Lexical, `"type":"html"`) || !strings.Contains(out.Lexical, `\u003ctable\u003e`) {
+		t.Errorf("table is not an HTML card:\n%s", out.Lexical)
+	}
+}
+
+func TestExtensionsWith(t *testing.T) {
+	site := Extensions{"gfm": true, "footnotes": true}
+	got := site.With(Extensions{"footnotes": false, "typographer": true})
+	want := Extensions{"gfm": true, "footnotes": false, "typographer": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("With = %v, want %v", got, want)
+	}
+	if !site["footnotes"] {
+		t.Error("With changed the site's extensions")
+	}
+}
+
+func TestExtensionsCheck(t *testing.T) {
+	if err := (Extensions{"gfm": true, "heading_ids": false}).Check(); err != nil {
+		t.Errorf("Check = %v", err)
+	}
+	err := Extensions{"tables": true}.Check()
+	want := `unknown Markdown extension "tables" (want attributes, definition_lists, footnotes, gfm, heading_ids, typographer)`
+	if err == nil || err.Error() != want {
+		t.Errorf("Check = %v, want %s", err, want)
+	}
+}
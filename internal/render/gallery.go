@@ -0,0 +1,149 @@
+// internal/render/gallery.go
+
+package render
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// galleryCard is a Ghost gallery card.
+type galleryCard struct {
+	ast.BaseBlock
+	Images  []picture
+	Caption string
+}
+
+var kindGalleryCard = ast.NewNodeKind("GalleryCard")
+
+func (n *galleryCard) Kind() ast.NodeKind { return kindGalleryCard }
+
+func (n *galleryCard) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Images": strconv.Itoa(len(n.Images))}, nil)
+}
+
+// maxGallery is the most images Ghost's editor holds in one gallery.
+const maxGallery = 9
+
+// galleryCards turns a paragraph of two or more images, one per line with
+// no blank lines between them, into a gallery, or into several of about
+// the same size if there are more than maxGallery images. An attribute
+// block after the last image sets the caption of the last gallery:
+//
+//	![](harbour.jpg)
+//	![](boats.jpg)
+//	![](nets.jpg)
+//	{caption="A morning at the harbour"}
+func (t *cards) galleryCards(p *ast.Paragraph, source []byte) []ast.Node {
+	var pics []picture
+	var tail strings.Builder
+	for c := p.FirstChild(); c != nil; c = c.NextSibling() {
+		if tail.Len() == 0 {
+			if isBlank(c, source) {
+				continue
+			}
+			if pic, ok := t.picture(c, source); ok {
+				pics = append(pics, pic)
+				continue
+			}
+		}
+		txt, ok := c.(*ast.Text)
+		if !ok {
+			return nil
+		}
+		tail.Write(txt.Value(source))
+	}
+	if len(pics) < 2 {
+		return nil
+	}
+	caption := ""
+	if rest := strings.TrimSpace(tail.String()); rest != "" {
+		m := attrBlockRe.FindStringSubmatch(rest)
+		if m == nil {
+			return nil
+		}
+		caption = parseAttrs(m[1])["caption"]
+	}
+	var out []ast.Node
+	n := (len(pics) + maxGallery - 1) / maxGallery
+	for i := range n {
+		out = append(out, &galleryCard{Images: pics[i*len(pics)/n : (i+1)*len(pics)/n]})
+	}
+	out[n-1].(*galleryCard).Caption = caption
+	return out
+}
+
+// galleryRows splits n images into rows the way Ghost's editor does: three
+// to a row, but never a single image left alone on the last row.
+func galleryRows(n int) [][2]int {
+	row := make([]int, n)
+	for i := range row {
+		row[i] = i / 3
+		if n > 1 && n%3 == 1 && i == n-2 {
+			row[i]++
+		}
+	}
+	var rows [][2]int // [start, end)
+	for i := 0; i < n; {
+		j := i
+		for j < n && row[j] == row[i] {
+			j++
+		}
+		rows = append(rows, [2]int{i, j})
+		i = j
+	}
+	return rows
+}
+
+func renderGalleryCard(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*galleryCard)
+	e := html.EscapeString
+
+	class := "kg-card kg-gallery-card kg-width-wide"
+	if n.Caption != "" {
+		class += " kg-card-hascaption"
+	}
+	fmt.Fprintf(w, `<figure class="%s"><div class="kg-gallery-container">`, class)
+	for _, r := range galleryRows(len(n.Images)) {
+		fmt.Fprint(w, `<div class="kg-gallery-row">`)
+		for _, p := range n.Images[r[0]:r[1]] {
+			if p.W > 0 && p.H > 0 {
+				ratio := strconv.FormatFloat(float64(p.W)/float64(p.H), 'f', -1, 64)
+				fmt.Fprintf(w, `<div class="kg-gallery-image" style="flex: %s 1 0%%;">`, ratio)
+			} else {
+				fmt.Fprint(w, `<div class="kg-gallery-image">`)
+			}
+			if p.Href != "" {
+				fmt.Fprintf(w, `<a href="%s">`, e(p.Href))
+			}
+			fmt.Fprintf(w, `<img src="%s"`, e(p.Src))
+			if p.W > 0 && p.H > 0 {
+				fmt.Fprintf(w, ` width="%d" height="%d"`, p.W, p.H)
+			}
+			fmt.Fprintf(w, ` loading="lazy" alt="%s"`, e(p.Alt))
+			if p.Title != "" {
+				fmt.Fprintf(w, ` title="%s"`, e(p.Title))
+			}
+			fmt.Fprint(w, ">")
+			if p.Href != "" {
+				fmt.Fprint(w, "</a>")
+			}
+			fmt.Fprint(w, "</div>")
+		}
+		fmt.Fprint(w, "</div>")
+	}
+	fmt.Fprint(w, "</div>")
+	if n.Caption != "" {
+		fmt.Fprintf(w, `<figcaption>%s</figcaption>`, e(n.Caption))
+	}
+	fmt.Fprint(w, "</figure>\n")
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,65 @@
+// internal/render/gallery_test.go
+
+package render
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGalleryRows(t *testing.T) {
+	tests := []struct {
+		n    int
+		want [][2]int
+	}{
+		{0, nil},
+		{1, [][2]int{{0, 1}}},
+		{2, [][2]int{{0, 2}}},
+		{3, [][2]int{{0, 3}}},
+		{4, [][2]int{{0, 2}, {2, 4}}},
+		{5, [][2]int{{0, 3}, {3, 5}}},
+		{7, [][2]int{{0, 3}, {3, 5}, {5, 7}}},
+		{9, [][2]int{{0, 3}, {3, 6}, {6, 9}}},
+	}
+	for _, tt := range tests {
+		if got := galleryRows(tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("galleryRows(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestGallerySplit(t *testing.T) {
+	tests := []struct {
+		images int
+		want   []int // images in each gallery
+	}{
+		{2, []int{2}},
+		{9, []int{9}},
+		{10, []int{5, 5}},
+		{19, []int{6, 6, 7}},
+	}
+	for _, tt := range tests {
+		var md strings.Builder
+		for i := range tt.images {
+			fmt.Fprintf(&md, "![](https://e.com/%d.jpg)\n", i)
+		}
+		md.WriteString("{caption=\"Harbour\"}\n")
+
+		out, err := HTML([]byte(md.String()), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int
+		for _, g := range strings.Split(out, "<figure")[1:] {
+			got = append(got, strings.Count(g, "<img"))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%d images: galleries of %v, want %v", tt.images, got, tt.want)
+		}
+		if strings.Count(out, "<figcaption>Harbour</figcaption>") != 1 || !strings.HasSuffix(out, "<figcaption>Harbour</figcaption></figure>\n") {
+			t.Errorf("%d images: want the caption once, on the last gallery:\n%s", tt.images, out)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// internal/render/highlight.go
+
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// Highlight configures syntax highlighting of fenced code blocks. The zero
+// value leaves them as plain code for Ghost.
+type Highlight struct {
+	Enabled     bool
+	Style       string // a chroma style; empty means github
+	Classes     bool   // CSS classes (see CSS) instead of inline styles
+	LineNumbers bool
+}
+
+func (h Highlight) style() string {
+	if h.Style == "" {
+		return "github"
+	}
+	return h.Style
+}
+
+// Check reports an unknown style.
+func (h Highlight) Check() error {
+	if _, ok := styles.Registry[h.style()]; !ok {
+		return fmt.Errorf("unknown style %q (see https://xyproto.github.io/splash/docs/)", h.style())
+	}
+	return nil
+}
+
+// CSS is the stylesheet for highlighting with classes, in a <style> tag
+// ready for Ghost's code injection.
+func CSS(style string) (string, error) {
+	h := Highlight{Style: style}
+	if err := h.Check(); err != nil {
+		return "", err
+	}
+	f := chromahtml.New(chromahtml.WithClasses(true), chromahtml.WithLineNumbers(true), chromahtml.LineNumbersInTable(true))
+	var b strings.Builder
+	if err := f.WriteCSS(&b, styles.Get(h.style())); err != nil {
+		return "", err
+	}
+	// chroma runs one rule into the next
+	css := strings.ReplaceAll(b.String(), "}/*", "}\n/*")
+	return "<style>\n" + css + "</style>\n", nil
+}
+
+// Ghost keeps HTML between these markers as an HTML card when it converts
+// a post, rather than turning the <pre> back into a plain code card.
+const (
+	htmlCardBegin = "<!--kg-card-begin: html-->\n"
+	htmlCardEnd   = "<!--kg-card-end: html-->\n"
+)
+
+// highlighter renders fenced code blocks with chroma. The info string
+// takes highlighted lines and a file name, and can switch line numbers:
+//
+//	```go {3-5,8} title="main.go" linenos=true
+type highlighter struct{ h Highlight }
+
+func (r highlighter) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.render)
+}
+
+var lineRangesRe = regexp.MustCompile(`\{([\d,\s-]+)\}`)
+
+// codeInfo is what a fenced code block's info string says.
+type codeInfo struct {
+	Lang        string
+	Lines       [][2]int // highlighted, inclusive
+	Title       string
+	LineNumbers *bool
+}
+
+func parseCodeInfo(info string) codeInfo {
+	var c codeInfo
+	c.Lang, _, _ = strings.Cut(strings.TrimSpace(info), " ")
+	c.Lang, _, _ = strings.Cut(c.Lang, "{")
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(info), c.Lang))
+
+	if m := lineRangesRe.FindStringSubmatch(rest); m != nil {
+		for _, r := range strings.Split(m[1], ",") {
+			from, to, found := strings.Cut(strings.TrimSpace(r), "-")
+			a, err := strconv.Atoi(from)
+			if err != nil {
+				continue
+			}
+			b := a
+			if found {
+				if b, err = strconv.Atoi(to); err != nil || b < a {
+					continue
+				}
+			}
+			c.Lines = append(c.Lines, [2]int{a, b})
+		}
+		rest = strings.Replace(rest, m[0], "", 1)
+	}
+	attrs := parseAttrs(rest)
+	c.Title = attrs["title"]
+	if c.Title == "" {
+		c.Title = attrs["filename"]
+	}
+	if v, ok := attrs["linenos"]; ok {
+		on := v == "true"
+		c.LineNumbers = &on
+	}
+	return c
+}
+
+func (r highlighter) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.FencedCodeBlock)
+	var info string
+	if n.Info != nil {
+		info = string(n.Info.Segment.Value(source))
+	}
+	c := parseCodeInfo(info)
+
+	var code bytes.Buffer
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		code.Write(seg.Value(source))
+	}
+
+	lexer := lexers.Get(c.Lang)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	it, err := chroma.Coalesce(lexer).Tokenise(nil, code.String())
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	numbers := r.h.LineNumbers
+	if c.LineNumbers != nil {
+		numbers = *c.LineNumbers
+	}
+	sort.Slice(c.Lines, func(i, j int) bool { return c.Lines[i][0] < c.Lines[j][0] })
+	f := chromahtml.New(
+		chromahtml.WithClasses(r.h.Classes),
+		chromahtml.WithLineNumbers(numbers),
+		chromahtml.LineNumbersInTable(true),
+		chromahtml.HighlightLines(c.Lines),
+	)
+
+	w.WriteString(htmlCardBegin)
+	if c.Title != "" {
+		w.WriteString(`<figure class="kg-card kg-code-card">`)
+	}
+	if err := f.Format(w, styles.Get(r.h.style()), it); err != nil {
+		return ast.WalkStop, err
+	}
+	if c.Title != "" {
+		fmt.Fprintf(w, "<figcaption>%s</figcaption></figure>", html.EscapeString(c.Title))
+	}
+	w.WriteString("\n" + htmlCardEnd)
+	return ast.WalkSkipChildren, nil
+}
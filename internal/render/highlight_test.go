@@ -0,0 +1,115 @@
+// internal/render/highlight_test.go
+
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCodeInfo(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		info string
+		want codeInfo
+	}{
+		{"", codeInfo{}},
+		{"go", codeInfo{Lang: "go"}},
+		{"go{3}", codeInfo{Lang: "go", Lines: [][2]int{{3, 3}}}},
+		{"go {1-2, 5,4-3} title=\"main.go\"", codeInfo{Lang: "go", Lines: [][2]int{{1, 2}, {5, 5}}, Title: "main.go"}},
+		{"py filename=app.py linenos=true", codeInfo{Lang: "py", Title: "app.py", LineNumbers: &on}},
+		{"py title='a b' filename=c linenos=false", codeInfo{Lang: "py", Title: "a b", LineNumbers: &off}},
+	}
+	for _, tt := range tests {
+		if got := parseCodeInfo(tt.info); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCodeInfo(%q) = %+v, want %+v", tt.info, got, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	const (
+		begin = "<!--kg-card-begin: html-->\n"
+		end   = "\n<!--kg-card-end: html-->\n"
+		x     = `<span class="nx">x</span> <span class="o">:=</span> <span class="mi">1</span>`
+	)
+	classes := Highlight{Enabled: true, Classes: true}
+	tests := []struct {
+		name, md string
+		h        Highlight
+		want     string
+	}{
+		{"off", "```go\nx := 1\n```\n", Highlight{}, "<pre><code class=\"language-go\">x := 1\n</code></pre>\n"},
+		{
+			"classes",
+			"```go\nx := 1\n```\n", classes,
+			begin + `<pre class="chroma"><code><span class="line"><span class="cl">` + x + "\n</span></span></code></pre>" + end,
+		},
+		{
+			"highlighted line and title",
+			"```go {2} title=\"main.go\"\nx := 1\nx := 1\n```\n", classes,
+			begin + `<figure class="kg-card kg-code-card"><pre class="chroma"><code><span class="line"><span class="cl">` + x + "\n</span></span>" +
+				`<span class="line hl"><span class="cl">` + x + "\n</span></span></code></pre><figcaption>main.go</figcaption></figure>" + end,
+		},
+		{
+			"line numbers from the info string",
+			"```go linenos=true\nx := 1\n```\n", classes,
+			begin + "<div class=\"chroma\">\n<table class=\"lntable\"><tr><td class=\"lntd\">\n<pre class=\"chroma\"><span class=\"lnt\">1\n</span></pre></td>\n" +
+				"<td class=\"lntd\">\n<pre class=\"chroma\"><code><span class=\"line\"><span class=\"cl\">" + x + "\n</span></span></code></pre></td></tr></table>\n</div>\n" + end,
+		},
+		{
+			"unknown language is escaped",
+			"```\nplain <b>\n```\n", classes,
+			begin + `<pre class="chroma"><code><span class="line"><span class="cl">plain &lt;b&gt;` + "\n</span></span></code></pre>" + end,
+		},
+		{
+			"inline styles",
+			"```go\nx := 1\n```\n", Highlight{Enabled: true},
+			begin + `<pre style="background-color:#fff;"><code><span style="display:flex;"><span>x <span style="color:#000;font-weight:bold">:=</span> <span style="color:#099">1</span>` +
+				"\n</span></span></code></pre>" + end,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HTML([]byte(tt.md), Options{Highlight: tt.h})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("HTML(%q)\n got %q\nwant %q", tt.md, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightLexical(t *testing.T) {
+	lexical, err := New("lexical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := lexical.Render([]byte("```go\nx := 1\n```\n"), Options{Highlight: Highlight{Enabled: true, Classes: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an HTML card holds the <pre> alone, without the markers around it
+	if !strings.Contains(out.Lexical, `{"html":"\u003cpre class=\"chroma\"\u003e`) || strings.Contains(out.Lexical, "kg-card-begin") {
+		t.Errorf("highlighted code is not an HTML card:\n%s", out.Lexical)
+	}
+}
+
+func TestCSS(t *testing.T) {
+	css, err := CSS("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(css, "<style>\n/* Background */ .bg { background-color: #ffffff; }\n") || !strings.HasSuffix(css, "</style>\n") {
+		t.Errorf("CSS is not the github style in a <style> tag:\n%s", css)
+	}
+	if strings.Contains(css, "}/*") {
+		t.Error("rules run into each other")
+	}
+	if _, err := CSS("nope"); err == nil || !strings.Contains(err.Error(), `unknown style "nope"`) {
+		t.Errorf("CSS(nope) error = %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+// internal/render/image.go
+
+package render
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/images"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// picture is one image in a card.
+type picture struct {
+	Src, Alt, Title string
+	Href            string // the image links here
+	W, H            int    // pixel size, if known
+}
+
+// picture reads an image, or a link around nothing but an image. Local
+// images get their width and height, so Ghost can build a srcset.
+func (t *cards) picture(n ast.Node, source []byte) (picture, bool) {
+	href := ""
+	if l, ok := n.(*ast.Link); ok {
+		if l.ChildCount() != 1 {
+			return picture{}, false
+		}
+		href, n = string(l.Destination), l.FirstChild()
+	}
+	img, ok := n.(*ast.Image)
+	if !ok {
+		return picture{}, false
+	}
+	dest := string(img.Destination)
+	p := picture{
+		Src:   dest,
+		Alt:   plainText(img, source),
+		Title: string(img.Title),
+		Href:  href,
+	}
+	if u, ok := t.o.URLs[dest]; ok {
+		p.Src = u
+	}
+	if u, ok := t.o.URLs[href]; ok {
+		p.Href = u
+	}
+	if path := images.Resolve(dest, t.o.Dir); path != "" {
+		p.W, p.H, _ = images.Dimensions(path, t.o.MaxWidth)
+	}
+	return p, true
+}
+
+// imageCard is a Ghost image card.
+type imageCard struct {
+	ast.BaseBlock
+	picture
+	Caption string
+	Width   string // "", wide or full
+}
+
+var kindImageCard = ast.NewNodeKind("ImageCard")
+
+func (n *imageCard) Kind() ast.NodeKind { return kindImageCard }
+
+func (n *imageCard) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Src": n.Src, "Width": n.Width}, nil)
+}
+
+// imageCard turns an image on its own line, optionally linked, into a card.
+// The title is the caption, and an attribute block sets the card width:
+//
+//	![A harbour at dawn](harbour.jpg "Taken from the lighthouse"){.wide}
+//	[![Logo](logo.png)](https://example.com){width=full caption="Our logo"}
+func (t *cards) imageCard(only ast.Node, attrs map[string]string, source []byte) ast.Node {
+	p, ok := t.picture(only, source)
+	if !ok {
+		return nil
+	}
+	c := &imageCard{picture: p, Caption: p.Title}
+
+	for _, class := range strings.Fields(attrs["class"]) {
+		if class == "wide" || class == "full" {
+			c.Width = class
+		}
+	}
+	if w, ok := attrs["width"]; ok && (w == "wide" || w == "full" || w == "regular") {
+		c.Width = strings.TrimPrefix(w, "regular")
+	}
+	if v, ok := attrs["caption"]; ok {
+		c.Caption = v
+	}
+	if v, ok := attrs["alt"]; ok {
+		c.Alt = v
+	}
+	return c
+}
+
+func renderImageCard(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*imageCard)
+	e := html.EscapeString
+
+	class := "kg-card kg-image-card"
+	if n.Width != "" {
+		class += " kg-width-" + n.Width
+	}
+	if n.Caption != "" {
+		class += " kg-card-hascaption"
+	}
+	fmt.Fprintf(w, `<figure class="%s">`, class)
+	if n.Href != "" {
+		fmt.Fprintf(w, `<a href="%s">`, e(n.Href))
+	}
+	fmt.Fprintf(w, `<img src="%s" class="kg-image" alt="%s" loading="lazy"`, e(n.Src), e(n.Alt))
+	if n.W > 0 && n.H > 0 {
+		fmt.Fprintf(w, ` width="%d" height="%d"`, n.W, n.H)
+	}
+	fmt.Fprint(w, ">")
+	if n.Href != "" {
+		fmt.Fprint(w, "</a>")
+	}
+	if n.Caption != "" {
+		fmt.Fprintf(w, `<figcaption>%s</figcaption>`, e(n.Caption))
+	}
+	fmt.Fprint(w, "</figure>\n")
+	return ast.WalkSkipChildren, nil
+}
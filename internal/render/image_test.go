@@ -0,0 +1,107 @@
+// internal/render/image_test.go
+
+package render
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// repoWithPNG makes a repository holding pic.png, 40×30, and returns its
+// path.
+func repoWithPNG(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, "pic.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, 40, 30))); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestImageCard(t *testing.T) {
+	dir := repoWithPNG(t)
+	urls := map[string]string{"pic.png": "https://ghost/content/images/pic.png"}
+	tests := []struct {
+		name, md string
+		maxWidth int
+		want     string
+	}{
+		{
+			"title is the caption",
+			"![A harbour](https://e.com/h.jpg \"At dawn\")\n", 0,
+			`<figure class="kg-card kg-image-card kg-card-hascaption"><img src="https://e.com/h.jpg" class="kg-image" alt="A harbour" loading="lazy"><figcaption>At dawn</figcaption></figure>` + "\n",
+		},
+		{
+			"no caption",
+			"![](https://e.com/a.png)\n", 0,
+			`<figure class="kg-card kg-image-card"><img src="https://e.com/a.png" class="kg-image" alt="" loading="lazy"></figure>` + "\n",
+		},
+		{
+			"wide class",
+			"![A](https://e.com/h.jpg){.wide}\n", 0,
+			`<figure class="kg-card kg-image-card kg-width-wide"><img src="https://e.com/h.jpg" class="kg-image" alt="A" loading="lazy"></figure>` + "\n",
+		},
+		{
+			"width, caption and alt attributes",
+			"![A harbour](https://e.com/h.jpg \"Title\"){width=full caption=\"Cap & more\" alt=\"Alt text\"}\n", 0,
+			`<figure class="kg-card kg-image-card kg-width-full kg-card-hascaption"><img src="https://e.com/h.jpg" class="kg-image" alt="Alt text" loading="lazy"><figcaption>Cap &amp; more</figcaption></figure>` + "\n",
+		},
+		{
+			"regular width",
+			"![](https://e.com/a.png){width=regular}\n", 0,
+			`<figure class="kg-card kg-image-card"><img src="https://e.com/a.png" class="kg-image" alt="" loading="lazy"></figure>` + "\n",
+		},
+		{
+			"linked image",
+			"[![Logo](https://e.com/l.png)](https://e.com)\n", 0,
+			`<figure class="kg-card kg-image-card"><a href="https://e.com"><img src="https://e.com/l.png" class="kg-image" alt="Logo" loading="lazy"></a></figure>` + "\n",
+		},
+		{
+			"alt is plain text",
+			"![A *harbour*](https://e.com/h.jpg 'say \"hi\"')\n", 0,
+			`<figure class="kg-card kg-image-card kg-card-hascaption"><img src="https://e.com/h.jpg" class="kg-image" alt="A harbour" loading="lazy"><figcaption>say &#34;hi&#34;</figcaption></figure>` + "\n",
+		},
+		{
+			"local image gets its URL and size",
+			"![Pic](pic.png \"Local\")\n", 0,
+			`<figure class="kg-card kg-image-card kg-card-hascaption"><img src="https://ghost/content/images/pic.png" class="kg-image" alt="Pic" loading="lazy" width="40" height="30"><figcaption>Local</figcaption></figure>` + "\n",
+		},
+		{
+			"size after resizing",
+			"![Pic](pic.png)\n", 20,
+			`<figure class="kg-card kg-image-card"><img src="https://ghost/content/images/pic.png" class="kg-image" alt="Pic" loading="lazy" width="20" height="15"></figure>` + "\n",
+		},
+		{
+			"image in a sentence",
+			"Text ![Pic](pic.png) inline.\n", 0,
+			`<p>Text <img src="https://ghost/content/images/pic.png" alt="Pic"> inline.</p>` + "\n",
+		},
+		{
+			"text after the attributes",
+			"![a](https://e.com/a.png){.wide} trailing\n", 0,
+			`<p><img src="https://e.com/a.png" alt="a">{.wide} trailing</p>` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HTML([]byte(tt.md), Options{Dir: dir, URLs: urls, MaxWidth: tt.maxWidth})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("HTML(%q)\n got %q\nwant %q", tt.md, got, tt.want)
+			}
+		})
+	}
+}
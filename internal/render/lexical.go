@@ -0,0 +1,409 @@
+// internal/render/lexical.go
+
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// node is one Lexical node. Maps marshal with sorted keys, so the same
+// post always gives the same document.
+type node = map[string]any
+
+// Text format bits, as Lexical defines them.
+const (
+	formatBold          = 1
+	formatItalic        = 2
+	formatStrikethrough = 4
+	formatCode          = 16
+)
+
+// lexicalDoc builds Ghost's Lexical document from a Markdown AST. Anything
+// it has no native node for (raw HTML, tables, an image in the middle of a
+// sentence…) becomes an HTML card holding what the HTML renderer makes of
+// it, so it survives as it is and stays editable as a card.
+type lexicalDoc struct {
+	source    []byte
+	html      renderer.Renderer
+	highlight bool // fenced code is highlighted HTML, not a code card
+}
+
+func toLexical(doc ast.Node, source []byte, r renderer.Renderer, highlight bool) (string, error) {
+	l := &lexicalDoc{source: source, html: r, highlight: highlight}
+	children, err := l.blocks(doc)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(node{"root": element("root", children, nil)})
+	return string(out), err
+}
+
+func element(typ string, children []any, extra node) node {
+	if children == nil {
+		children = []any{}
+	}
+	n := node{"children": children, "direction": "ltr", "format": "", "indent": 0, "type": typ, "version": 1}
+	for k, v := range extra {
+		n[k] = v
+	}
+	return n
+}
+
+func card(typ string, fields node) node {
+	n := node{"type": typ, "version": 1}
+	for k, v := range fields {
+		n[k] = v
+	}
+	return n
+}
+
+func (l *lexicalDoc) blocks(parent ast.Node) ([]any, error) {
+	var out []any
+	for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+		b, err := l.block(n)
+		if err != nil {
+			return nil, err
+		}
+		if b != nil {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+func (l *lexicalDoc) block(n ast.Node) (node, error) {
+	switch n := n.(type) {
+	case *ast.Paragraph, *ast.TextBlock:
+		if inline, ok := l.inlines(n, 0); ok {
+			if len(inline) == 0 {
+				return nil, nil
+			}
+			return element("paragraph", inline, nil), nil
+		}
+	case *ast.Heading:
+		if inline, ok := l.inlines(n, 0); ok {
+			return element("extended-heading", inline, node{"tag": fmt.Sprintf("h%d", n.Level)}), nil
+		}
+	case *ast.Blockquote:
+		if inline, ok := l.quote(n); ok {
+			return element("extended-quote", inline, nil), nil
+		}
+	case *ast.List:
+		if list, ok := l.list(n, 0); ok {
+			return list, nil
+		}
+	case *ast.FencedCodeBlock:
+		if l.highlight {
+			c, err := l.htmlCard(n)
+			if err == nil {
+				h := strings.TrimPrefix(c["html"].(string), htmlCardBegin)
+				c["html"] = strings.TrimSpace(strings.TrimSuffix(h, strings.TrimSpace(htmlCardEnd)))
+			}
+			return c, err
+		}
+		var info codeInfo
+		if n.Info != nil {
+			info = parseCodeInfo(string(n.Info.Segment.Value(l.source)))
+		}
+		return card("codeblock", node{"code": strings.TrimSuffix(l.lines(n), "\n"), "language": info.Lang, "caption": html.EscapeString(info.Title)}), nil
+	case *ast.CodeBlock:
+		return card("codeblock", node{"code": strings.TrimSuffix(l.lines(n), "\n"), "language": "", "caption": ""}), nil
+	case *ast.ThematicBreak:
+		return card("horizontalrule", nil), nil
+	case *ast.HTMLBlock:
+		raw := l.lines(n)
+		if n.HasClosure() {
+			raw += string(n.ClosureLine.Value(l.source))
+		}
+		return card("html", node{"html": strings.TrimRight(raw, "\n")}), nil
+	case *imageCard:
+		width := n.Width
+		if width == "" {
+			width = "regular"
+		}
+		return card("image", node{
+			"src": n.Src, "width": dimension(n.W), "height": dimension(n.H), "title": n.Title, "alt": n.Alt,
+			"caption": html.EscapeString(n.Caption), "cardWidth": width, "href": n.Href,
+		}), nil
+	case *galleryCard:
+		var pics []any
+		for row, r := range galleryRows(len(n.Images)) {
+			for _, p := range n.Images[r[0]:r[1]] {
+				pics = append(pics, node{
+					"row": row, "src": p.Src, "width": dimension(p.W), "height": dimension(p.H), "fileName": path.Base(p.Src),
+					"title": p.Title, "alt": p.Alt, "caption": "", "href": p.Href,
+				})
+			}
+		}
+		return card("gallery", node{"images": pics, "caption": html.EscapeString(n.Caption)}), nil
+	case *mediaCard:
+		switch n.Card {
+		case "audio":
+			return card("audio", node{"src": n.Src, "title": n.Title, "duration": 0, "mimeType": "", "thumbnailSrc": ""}), nil
+		case "video":
+			return card("video", node{
+				"src": n.Src, "caption": html.EscapeString(n.Caption), "fileName": n.FileName, "mimeType": "",
+				"duration": 0, "thumbnailSrc": "", "cardWidth": "regular", "loop": false,
+			}), nil
+		}
+		return card("file", node{
+			"src": n.Src, "fileTitle": n.Title, "fileCaption": n.Caption, "fileName": n.FileName, "fileSize": n.Size,
+		}), nil
+	case *shortcode:
+		return l.shortcode(n)
+	}
+	return l.htmlCard(n)
+}
+
+// shortcode maps a card shortcode onto the Lexical card of the same name.
+func (l *lexicalDoc) shortcode(n *shortcode) (node, error) {
+	a := n.Attrs
+	var inner bytes.Buffer
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if err := l.html.Render(&inner, l.source, c); err != nil {
+			return nil, err
+		}
+	}
+	body := strings.TrimSpace(inner.String())
+	or := func(v, def string) string {
+		if v == "" {
+			return def
+		}
+		return v
+	}
+
+	switch n.Name {
+	case "callout":
+		return card("callout", node{"calloutText": body, "calloutEmoji": a["emoji"], "backgroundColor": or(a["color"], "grey")}), nil
+	case "toggle":
+		return card("toggle", node{"heading": html.EscapeString(a["heading"]), "content": body}), nil
+	case "button":
+		return card("button", node{"buttonText": a["text"], "alignment": or(a["align"], "left"), "buttonUrl": a["url"]}), nil
+	case "bookmark":
+		return card("bookmark", node{
+			"url":     a["url"],
+			"caption": html.EscapeString(a["caption"]),
+			"metadata": node{
+				"title": a["title"], "description": a["description"], "icon": a["icon"],
+				"thumbnail": a["thumbnail"], "author": a["author"], "publisher": a["publisher"],
+			},
+		}), nil
+	case "header":
+		return card("header", node{
+			"size": or(a["size"], "small"), "style": or(a["style"], "dark"),
+			"header": html.EscapeString(a["heading"]), "subheader": html.EscapeString(a["subheading"]),
+			"buttonEnabled": a["button_url"] != "", "buttonText": a["button_text"], "buttonUrl": a["button_url"],
+			"backgroundImageSrc": a["background_image"],
+		}), nil
+	case "product":
+		rating, _ := strconv.Atoi(a["rating"])
+		stars := rating
+		if stars == 0 {
+			stars = 5
+		}
+		return card("product", node{
+			"productImageSrc": a["image"], "productImageWidth": nil, "productImageHeight": nil,
+			"productTitle": html.EscapeString(a["title"]), "productDescription": body,
+			"productRatingEnabled": rating > 0, "productStarRating": stars,
+			"productButtonEnabled": a["button_url"] != "", "productButton": a["button_text"], "productUrl": a["button_url"],
+		}), nil
+	}
+	return l.htmlCard(n)
+}
+
+// htmlCard renders n with the HTML renderer into an HTML card.
+func (l *lexicalDoc) htmlCard(n ast.Node) (node, error) {
+	var buf bytes.Buffer
+	if err := l.html.Render(&buf, l.source, n); err != nil {
+		return nil, err
+	}
+	return card("html", node{"html": strings.TrimRight(buf.String(), "\n")}), nil
+}
+
+func (l *lexicalDoc) lines(n ast.Node) string {
+	var b strings.Builder
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		b.Write(seg.Value(l.source))
+	}
+	return b.String()
+}
+
+// quote flattens a blockquote into the inline children of a Lexical
+// quote, with a blank line between paragraphs. Anything but paragraphs
+// makes it an HTML card.
+func (l *lexicalDoc) quote(q *ast.Blockquote) ([]any, bool) {
+	var out []any
+	for c := q.FirstChild(); c != nil; c = c.NextSibling() {
+		if _, ok := c.(*ast.Paragraph); !ok {
+			return nil, false
+		}
+		inline, ok := l.inlines(c, 0)
+		if !ok {
+			return nil, false
+		}
+		if len(out) > 0 {
+			out = append(out, card("linebreak", nil), card("linebreak", nil))
+		}
+		out = append(out, inline...)
+	}
+	return out, true
+}
+
+// list builds a Lexical list. A nested list goes in a list item of its
+// own, after the item it belongs to, the way Lexical nests lists.
+func (l *lexicalDoc) list(n *ast.List, depth int) (node, bool) {
+	typ, tag := "bullet", "ul"
+	if n.IsOrdered() {
+		typ, tag = "number", "ol"
+	}
+	start := n.Start
+	if start == 0 {
+		start = 1
+	}
+	var items []any
+	value := start
+	for it := n.FirstChild(); it != nil; it = it.NextSibling() {
+		var inline []any
+		var nested []node
+		for c := it.FirstChild(); c != nil; c = c.NextSibling() {
+			switch c := c.(type) {
+			case *ast.TextBlock, *ast.Paragraph:
+				in, ok := l.inlines(c, 0)
+				if !ok {
+					return nil, false
+				}
+				if len(inline) > 0 {
+					inline = append(inline, card("linebreak", nil))
+				}
+				inline = append(inline, in...)
+			case *ast.List:
+				sub, ok := l.list(c, depth+1)
+				if !ok {
+					return nil, false
+				}
+				nested = append(nested, sub)
+			default:
+				return nil, false
+			}
+		}
+		items = append(items, element("listitem", inline, node{"indent": depth, "value": value}))
+		for _, sub := range nested {
+			items = append(items, element("listitem", []any{sub}, node{"indent": depth, "value": value + 1}))
+		}
+		value++
+	}
+	return element("list", items, node{"listType": typ, "start": start, "tag": tag, "indent": depth}), true
+}
+
+// inlines converts the inline children of parent. It reports false if
+// one of them has no Lexical equivalent.
+func (l *lexicalDoc) inlines(parent ast.Node, format int) ([]any, bool) {
+	var out []any
+	add := func(s string, format int) {
+		if s == "" {
+			return
+		}
+		if len(out) > 0 {
+			if prev, ok := out[len(out)-1].(node); ok && prev["type"] == "extended-text" && prev["format"] == format {
+				prev["text"] = prev["text"].(string) + s
+				return
+			}
+		}
+		out = append(out, textNode(s, format))
+	}
+	// nested adds the converted children of c, merging text where it can.
+	nested := func(c ast.Node, format int) bool {
+		in, ok := l.inlines(c, format)
+		if !ok {
+			return false
+		}
+		for _, n := range in {
+			if t := n.(node); t["type"] == "extended-text" {
+				add(t["text"].(string), t["format"].(int))
+				continue
+			}
+			out = append(out, n)
+		}
+		return true
+	}
+
+	for c := parent.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			add(html.UnescapeString(string(util.UnescapePunctuations(c.Value(l.source)))), format)
+			switch {
+			case c.HardLineBreak():
+				out = append(out, card("linebreak", nil))
+			case c.SoftLineBreak():
+				add(" ", format)
+			}
+		case *ast.String:
+			add(stringValue(c), format)
+		case *ast.CodeSpan:
+			add(plainText(c, l.source), format|formatCode)
+		case *ast.Emphasis:
+			bit := formatItalic
+			if c.Level >= 2 {
+				bit = formatBold
+			}
+			if !nested(c, format|bit) {
+				return nil, false
+			}
+		case *east.Strikethrough:
+			if !nested(c, format|formatStrikethrough) {
+				return nil, false
+			}
+		case *ast.Link:
+			in, ok := l.inlines(c, format)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, link(string(c.Destination), string(c.Title), in))
+		case *ast.AutoLink:
+			url := string(c.URL(l.source))
+			if c.AutoLinkType == ast.AutoLinkEmail && !strings.HasPrefix(url, "mailto:") {
+				url = "mailto:" + url
+			}
+			out = append(out, link(url, "", []any{textNode(string(c.Label(l.source)), format)}))
+		default:
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+// dimension is a pixel size, or null when it isn't known.
+func dimension(px int) any {
+	if px == 0 {
+		return nil
+	}
+	return px
+}
+
+func textNode(s string, format int) node {
+	return node{
+		"detail": 0, "format": format, "mode": "normal", "style": "",
+		"text": s, "type": "extended-text", "version": 1,
+	}
+}
+
+func link(url, title string, children []any) node {
+	var t any
+	if title != "" {
+		t = title
+	}
+	return element("link", children, node{"url": url, "rel": nil, "target": nil, "title": t})
+}
@@ -0,0 +1,142 @@
+// internal/render/lexical_test.go
+
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Builders for the expected documents, in the shape toLexical writes.
+
+func lexText(s string, format int) string {
+	return fmt.Sprintf(`{"detail":0,"format":%d,"mode":"normal","style":"","text":%q,"type":"extended-text","version":1}`, format, s)
+}
+
+func lexElement(typ, extra string, children ...string) string {
+	if extra != "" {
+		extra += ","
+	}
+	return `{"children":[` + strings.Join(children, ",") + `],"direction":"ltr","format":"","indent":0,` + extra + `"type":"` + typ + `","version":1}`
+}
+
+const lexBreak = `{"type":"linebreak","version":1}`
+
+func TestLexical(t *testing.T) {
+	tests := []struct {
+		name, md string
+		want     []string // the root's children
+	}{
+		{"empty", "", nil},
+		{
+			"heading and formats",
+			"# Title\n\nA **b** *i* `c` [l](https://e.com \"T\").\n",
+			[]string{
+				lexElement("extended-heading", `"tag":"h1"`, lexText("Title", 0)),
+				lexElement("paragraph", "",
+					lexText("A ", 0), lexText("b", 1), lexText(" ", 0), lexText("i", 2), lexText(" ", 0), lexText("c", 16), lexText(" ", 0),
+					lexElement("link", `"rel":null,"target":null,"title":"T","url":"https://e.com"`, lexText("l", 0)),
+					lexText(".", 0)),
+			},
+		},
+		{"nested formats", "***both***\n", []string{lexElement("paragraph", "", lexText("both", 3))}},
+		{"soft break is a space", "one\ntwo\n", []string{lexElement("paragraph", "", lexText("one two", 0))}},
+		{"hard break", "one\\\ntwo\n", []string{lexElement("paragraph", "", lexText("one", 0), lexBreak, lexText("two", 0))}},
+		{
+			"quote paragraphs",
+			"> one\n>\n> two\n",
+			[]string{lexElement("extended-quote", "", lexText("one", 0), lexBreak, lexBreak, lexText("two", 0))},
+		},
+		{
+			"nested list",
+			"3. a\n   - b\n4. c\n",
+			[]string{lexElement("list", `"listType":"number","start":3,"tag":"ol"`,
+				lexElement("listitem", `"value":3`, lexText("a", 0)),
+				lexElement("listitem", `"value":4`,
+					strings.Replace(lexElement("list", `"listType":"bullet","start":1,"tag":"ul"`,
+						strings.Replace(lexElement("listitem", `"value":1`, lexText("b", 0)), `"indent":0`, `"indent":1`, 1)),
+						`"indent":0`, `"indent":1`, 1)),
+				lexElement("listitem", `"value":4`, lexText("c", 0)),
+			)},
+		},
+		{
+			"code card with caption",
+			"```go {title=\"main.go\"}\nx := 1\n```\n",
+			[]string{`{"caption":"main.go","code":"x := 1","language":"go","type":"codeblock","version":1}`},
+		},
+		{"indented code", "    x := 1\n", []string{`{"caption":"","code":"x := 1","language":"","type":"codeblock","version":1}`}},
+		{"rule", "---\n", []string{`{"type":"horizontalrule","version":1}`}},
+		{"raw HTML", "<div>raw</div>\n", []string{`{"html":"<div>raw</div>","type":"html","version":1}`}},
+		{
+			"inline HTML makes an HTML card",
+			"A <kbd>key</kbd>.\n",
+			[]string{`{"html":"<p>A <kbd>key</kbd>.</p>","type":"html","version":1}`},
+		},
+		{
+			"image card",
+			"[![A](https://e.com/h.jpg \"Cap\")](https://e.com){.wide}\n",
+			[]string{`{"alt":"A","caption":"Cap","cardWidth":"wide","height":null,"href":"https://e.com","src":"https://e.com/h.jpg","title":"Cap","type":"image","version":1,"width":null}`},
+		},
+		{
+			"gallery",
+			"![a](https://e.com/a.png)\n![b](https://e.com/b.png \"B\")\n{caption=\"Two & more\"}\n",
+			[]string{`{"caption":"Two &amp; more","images":[` +
+				`{"alt":"a","caption":"","fileName":"a.png","height":null,"href":"","row":0,"src":"https://e.com/a.png","title":"","width":null},` +
+				`{"alt":"b","caption":"","fileName":"b.png","height":null,"href":"","row":0,"src":"https://e.com/b.png","title":"B","width":null}` +
+				`],"type":"gallery","version":1}`},
+		},
+	}
+	lexical, err := New("lexical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := lexical.Render([]byte(tt.md), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.Body() != out.Lexical || out.HTML == "" && tt.md != "" {
+				t.Errorf("Body() = %q, want the Lexical document, with the HTML alongside", out.Body())
+			}
+			var got struct {
+				Root struct {
+					Children []any `json:"children"`
+				} `json:"root"`
+			}
+			if err := json.Unmarshal([]byte(out.Lexical), &got); err != nil {
+				t.Fatal(err)
+			}
+			var want []any
+			if err := json.Unmarshal([]byte("["+strings.Join(tt.want, ",")+"]"), &want); err != nil {
+				t.Fatalf("bad want: %v", err)
+			}
+			if len(got.Root.Children) == 0 && len(want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got.Root.Children, want) {
+				raw, _ := json.Marshal(got.Root.Children)
+				t.Errorf("Lexical of %q\n got %s\nwant [%s]", tt.md, raw, strings.Join(tt.want, ","))
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, name := range []string{"", "html"} {
+		r, err := New(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := r.Render([]byte("*hi*\n"), Options{})
+		if err != nil || out.Lexical != "" || out.Body() != "<p><em>hi</em></p>\n" {
+			t.Errorf("New(%q).Render = %+v, %v, want HTML only", name, out, err)
+		}
+	}
+	if _, err := New("markdown"); err == nil || !strings.Contains(err.Error(), `unknown renderer "markdown"`) {
+		t.Errorf("New(markdown) error = %v", err)
+	}
+}
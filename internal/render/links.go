@@ -0,0 +1,163 @@
+// internal/render/links.go
+
+package render
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// Links resolves a link to another post: target is a path to a .md file
+// as written (URL-decoded, without its #fragment), or the page name of a
+// [[wiki link]]. It returns the URL to link to, or "" to leave a Markdown
+// link as written and replace a wiki link by its text. An error is
+// reported against the line of the link.
+type Links func(target string, wiki bool) (string, error)
+
+// wikiLink is an Obsidian-style link: [[Page]], [[Page|label]] or
+// [[Page#Heading]].
+type wikiLink struct {
+	ast.BaseInline
+	Target, Heading, Label string
+	Line                   int
+}
+
+var kindWikiLink = ast.NewNodeKind("WikiLink")
+
+func (n *wikiLink) Kind() ast.NodeKind { return kindWikiLink }
+
+func (n *wikiLink) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Target": n.Target}, nil)
+}
+
+type wikiLinkParser struct{}
+
+func (wikiLinkParser) Trigger() []byte { return []byte{'['} }
+
+func (wikiLinkParser) Parse(_ ast.Node, block text.Reader, _ parser.Context) ast.Node {
+	line, seg := block.PeekLine()
+	if !bytes.HasPrefix(line, []byte("[[")) {
+		return nil
+	}
+	end := bytes.Index(line, []byte("]]"))
+	if end < 0 {
+		return nil
+	}
+	inner := string(line[2:end])
+	if strings.TrimSpace(inner) == "" || strings.ContainsAny(inner, "[]") {
+		return nil
+	}
+	target, label, _ := strings.Cut(inner, "|")
+	target, heading, _ := strings.Cut(target, "#")
+	n := &wikiLink{
+		Target:  strings.TrimSpace(target),
+		Heading: strings.TrimSpace(heading),
+		Label:   strings.TrimSpace(label),
+		Line:    lineOf(block.Source(), seg.Start),
+	}
+	if n.Label == "" {
+		n.Label = n.Target
+		if n.Heading != "" {
+			n.Label += " > " + n.Heading
+		}
+	}
+	block.Advance(end + 2)
+	return n
+}
+
+// links points links to other posts at their Ghost URLs (see Options.Links)
+// and turns wiki links into plain links.
+type links struct{ o Options }
+
+func (t *links) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var wikis []*wikiLink
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *wikiLink:
+			wikis = append(wikis, n)
+		case *ast.Link:
+			target, fragment, ok := postLink(string(n.Destination))
+			if !ok {
+				break
+			}
+			u, err := t.o.Links(target, false)
+			if err != nil {
+				report(pc, &Error{Line: linkLine(n, source), Msg: err.Error()})
+			}
+			if u != "" {
+				n.Destination = []byte(u + fragment)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, w := range wikis {
+		u, err := t.o.Links(w.Target, true)
+		if err != nil {
+			report(pc, &Error{Line: w.Line, Msg: err.Error()})
+		}
+		label := ast.NewString([]byte(w.Label))
+		if u == "" {
+			w.Parent().ReplaceChild(w.Parent(), w, label)
+			continue
+		}
+		if w.Heading != "" {
+			u += "#" + headingID(w.Heading)
+		}
+		l := ast.NewLink()
+		l.Destination = []byte(u)
+		l.AppendChild(l, label)
+		w.Parent().ReplaceChild(w.Parent(), w, l)
+	}
+}
+
+// postLink reports whether dest is a relative link to a Markdown file, and
+// splits it into the decoded path and the #fragment.
+func postLink(dest string) (target, fragment string, ok bool) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", "", false
+	}
+	if ext := path.Ext(u.Path); ext != ".md" && ext != ".markdown" {
+		return "", "", false
+	}
+	if u.Fragment != "" {
+		fragment = "#" + u.EscapedFragment()
+	}
+	return u.Path, fragment, true
+}
+
+// linkLine finds the line of n: the first line of its paragraph holding
+// its destination.
+func linkLine(n *ast.Link, source []byte) int {
+	var b ast.Node = n
+	for b != nil && b.Type() != ast.TypeBlock {
+		b = b.Parent()
+	}
+	if b == nil || b.Lines().Len() == 0 {
+		return 0
+	}
+	start := b.Lines().At(0).Start
+	if i := bytes.Index(source[start:], n.Destination); i >= 0 {
+		start += i
+	}
+	return lineOf(source, start)
+}
+
+var nonIDRe = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// headingID is the id Ghost gives a heading with this text.
+func headingID(heading string) string {
+	return strings.Trim(nonIDRe.ReplaceAllString(strings.ToLower(heading), "-"), "-")
+}
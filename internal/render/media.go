@@ -0,0 +1,124 @@
+// internal/render/media.go
+
+package render
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/images"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// mediaCard is a Ghost audio, video or file card.
+type mediaCard struct {
+	ast.BaseBlock
+	Card     string // audio, video or file
+	Src      string
+	Title    string
+	Caption  string
+	FileName string
+	Size     int64
+}
+
+var kindMediaCard = ast.NewNodeKind("MediaCard")
+
+func (n *mediaCard) Kind() ast.NodeKind { return kindMediaCard }
+
+func (n *mediaCard) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Card": n.Card, "Src": n.Src}, nil)
+}
+
+// mediaCard turns a link (or image) to a local audio, video or other
+// non-image file into a card:
+//
+//	[Episode 12](audio/ep12.mp3)
+//	![Demo](demo.mp4 "What it looks like")
+//	[The slides](slides.pdf)
+//
+// The link text is the title; the link title, if any, the caption.
+func (t *cards) mediaCard(only ast.Node, source []byte) ast.Node {
+	var dest, title []byte
+	switch l := only.(type) {
+	case *ast.Link:
+		dest, title = l.Destination, l.Title
+	case *ast.Image:
+		dest, title = l.Destination, l.Title
+	default:
+		return nil
+	}
+	path := images.Attachment(string(dest), t.o.Dir, t.o.Limits)
+	if path == "" || images.IsImage(path) {
+		return nil
+	}
+
+	c := &mediaCard{
+		Card:     "file",
+		Src:      string(dest),
+		Title:    plainText(only, source),
+		Caption:  string(title),
+		FileName: filepath.Base(path),
+	}
+	switch {
+	case images.IsAudio(path):
+		c.Card = "audio"
+	case images.IsVideo(path):
+		c.Card = "video"
+	}
+	if u, ok := t.o.URLs[c.Src]; ok {
+		c.Src = u
+	}
+	if info, err := os.Stat(path); err == nil {
+		c.Size = info.Size()
+	}
+	if c.Title == "" {
+		c.Title = strings.TrimSuffix(c.FileName, filepath.Ext(c.FileName))
+	}
+	return c
+}
+
+func renderMediaCard(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*mediaCard)
+	e := html.EscapeString
+	switch n.Card {
+	case "audio":
+		fmt.Fprintf(w, `<div class="kg-card kg-audio-card"><audio src="%s" preload="metadata"></audio>`+
+			`<div class="kg-audio-player-container"><div class="kg-audio-title">%s</div></div></div>`+"\n",
+			e(n.Src), e(n.Title))
+	case "video":
+		fmt.Fprintf(w, `<figure class="kg-card kg-video-card"><div class="kg-video-container">`+
+			`<video src="%s" title="%s" playsinline preload="metadata" controls></video></div>`, e(n.Src), e(n.Title))
+		if n.Caption != "" {
+			fmt.Fprintf(w, `<figcaption>%s</figcaption>`, e(n.Caption))
+		}
+		fmt.Fprint(w, "</figure>\n")
+	default:
+		fmt.Fprintf(w, `<div class="kg-card kg-file-card"><a class="kg-file-card-container" href="%s" title="Download" download>`+
+			`<div class="kg-file-card-contents"><div class="kg-file-card-title">%s</div>`, e(n.Src), e(n.Title))
+		if n.Caption != "" {
+			fmt.Fprintf(w, `<div class="kg-file-card-caption">%s</div>`, e(n.Caption))
+		}
+		fmt.Fprintf(w, `<div class="kg-file-card-metadata"><div class="kg-file-card-filename">%s</div>`+
+			`<div class="kg-file-card-filesize">%s</div></div></div></a></div>`+"\n", e(n.FileName), fileSize(n.Size))
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// fileSize formats n the way Ghost's file card does.
+func fileSize(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d Bytes", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%d KB", (n+512)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+}
@@ -0,0 +1,153 @@
+// internal/render/render.go
+
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/images"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Options describe where a post's files are and what became of them.
+type Options struct {
+	Dir      string            // the post's directory; local paths are relative to it
+	URLs     map[string]string // uploaded URLs, keyed by reference as written
+	MaxWidth int               // images wider than this are scaled down on upload (images.max_width)
+	Limits   images.Limits     // which linked files are attachments (uploads.*_types)
+
+	// Extensions switches Markdown extensions on or off; nil means the
+	// defaults.
+	Extensions Extensions
+
+	// Highlight configures syntax highlighting of fenced code blocks.
+	Highlight Highlight
+
+	// Links resolves links to other posts; nil leaves them as written.
+	Links Links
+}
+
+// Output is a rendered post.
+type Output struct {
+	HTML    string // always set; plan diffs it against Ghost's HTML
+	Lexical string // the Lexical document, from the lexical renderer only
+}
+
+// Body is what is sent to Ghost as the post's content: the Lexical
+// document if there is one, else the HTML.
+func (o Output) Body() string {
+	if o.Lexical != "" {
+		return o.Lexical
+	}
+	return o.HTML
+}
+
+// Renderer turns a post's Markdown into what is sent to Ghost.
+type Renderer interface {
+	Render(md []byte, o Options) (Output, error)
+}
+
+// New returns the renderer called name (the renderer: config key): html,
+// the default, or lexical.
+func New(name string) (Renderer, error) {
+	switch name {
+	case "", "html":
+		return htmlRenderer{}, nil
+	case "lexical":
+		return lexicalRenderer{}, nil
+	}
+	return nil, fmt.Errorf("unknown renderer %q (want html or lexical)", name)
+}
+
+// htmlRenderer sends HTML, which Ghost converts into Lexical itself.
+type htmlRenderer struct{}
+
+func (htmlRenderer) Render(md []byte, o Options) (Output, error) {
+	h, err := HTML(md, o)
+	return Output{HTML: h}, err
+}
+
+// lexicalRenderer sends the Lexical document built from the Markdown, so
+// nothing is lost to Ghost's HTML import.
+type lexicalRenderer struct{}
+
+func (lexicalRenderer) Render(md []byte, o Options) (Output, error) {
+	gm := markdown(o)
+	doc, err := parse(gm, md)
+	if err != nil {
+		return Output{}, err
+	}
+	var out bytes.Buffer
+	if err := gm.Renderer().Render(&out, md, doc); err != nil {
+		return Output{}, err
+	}
+	lex, err := toLexical(doc, md, gm.Renderer(), o.Highlight.Enabled)
+	if err != nil {
+		return Output{}, err
+	}
+	return Output{HTML: out.String(), Lexical: lex}, nil
+}
+
+// HTML turns md into the HTML sent to Ghost. Image and attachment
+// references point at the URLs in o.URLs (see images.Rewriter), and an
+// image, or a local audio, video or other file, on its own line becomes a
+// Ghost card, as does a card shortcode. Raw HTML is passed through.
+// Mistakes in shortcodes are returned as *Error, joined.
+func HTML(md []byte, o Options) (string, error) {
+	gm := markdown(o)
+	doc, err := parse(gm, md)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := gm.Renderer().Render(&out, md, doc); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func parse(gm goldmark.Markdown, md []byte) (ast.Node, error) {
+	pc := parser.NewContext()
+	doc := gm.Parser().Parse(text.NewReader(md), parser.WithContext(pc))
+	errs, _ := pc.Get(errorsKey).([]error)
+	return doc, errors.Join(errs...)
+}
+
+func markdown(o Options) goldmark.Markdown {
+	opts := o.Extensions.options()
+	if o.Highlight.Enabled {
+		opts = append(opts, goldmark.WithRendererOptions(
+			renderer.WithNodeRenderers(util.Prioritized(highlighter{o.Highlight}, 100)),
+		))
+	}
+	if o.Links != nil {
+		// without a resolver, links and [[text]] are left as written
+		opts = append(opts, goldmark.WithParserOptions(
+			parser.WithInlineParsers(util.Prioritized(wikiLinkParser{}, 199)),
+			parser.WithASTTransformers(util.Prioritized(&links{o}, 60)),
+		))
+	}
+	return goldmark.New(append(opts,
+		goldmark.WithParserOptions(
+			parser.WithBlockParsers(util.Prioritized(shortcodeParser{}, 50)),
+			parser.WithASTTransformers(
+				util.Prioritized(shortcodes{}, 40),
+				util.Prioritized(&cards{o}, 50),
+				util.Prioritized(images.Rewriter(o.URLs), 100),
+			),
+		),
+		goldmark.WithRendererOptions(
+			renderer.WithNodeRenderers(util.Prioritized(cardRenderer{}, 100)),
+			html.WithUnsafe(),
+		),
+	)...)
+}
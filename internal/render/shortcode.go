@@ -0,0 +1,384 @@
+// internal/render/shortcode.go
+
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Error is a mistake in a post's Markdown. Line counts from the first line
+// of the Markdown, after any front-matter.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("line %d: %s", e.Line, e.Msg) }
+
+var errorsKey = parser.NewContextKey()
+
+// report records a mistake found while parsing, for parse to return.
+func report(pc parser.Context, err error) {
+	errs, _ := pc.Get(errorsKey).([]error)
+	pc.Set(errorsKey, append(errs, err))
+}
+
+// lineOf is the line of source that pos is on, counting from 1.
+func lineOf(source []byte, pos int) int {
+	return bytes.Count(source[:pos], []byte("\n")) + 1
+}
+
+// shortcode is a Ghost card written as a fenced directive:
+//
+//	:::callout{emoji="💡" color="blue"}
+//	Markdown, rendered inside the card.
+//	:::
+//
+// A shortcode inside another one takes a longer fence (::::toggle … ::::)
+// around a shorter one.
+type shortcode struct {
+	ast.BaseBlock
+	Name   string
+	Attrs  map[string]string
+	Line   int
+	fence  int  // number of colons
+	closed bool // the closing fence was found
+}
+
+var kindShortcode = ast.NewNodeKind("Shortcode")
+
+func (n *shortcode) Kind() ast.NodeKind { return kindShortcode }
+
+func (n *shortcode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Name": n.Name}, nil)
+}
+
+// shortcodeSpec is what a card takes: its attributes, the required ones
+// first, and whether it has Markdown content.
+type shortcodeSpec struct {
+	required, optional []string
+	body               bool
+	check              func(a map[string]string) string
+}
+
+var shortcodeSpecs = map[string]shortcodeSpec{
+	"callout": {
+		optional: []string{"emoji", "color"},
+		body:     true,
+		check: func(a map[string]string) string {
+			return oneOf(a, "color", "grey", "white", "blue", "green", "yellow", "red", "pink", "purple", "accent")
+		},
+	},
+	"toggle": {required: []string{"heading"}, body: true},
+	"button": {
+		required: []string{"url", "text"},
+		optional: []string{"align"},
+		check:    func(a map[string]string) string { return oneOf(a, "align", "left", "center") },
+	},
+	"bookmark": {
+		required: []string{"url", "title"},
+		optional: []string{"description", "icon", "thumbnail", "author", "publisher", "caption"},
+	},
+	"header": {
+		required: []string{"heading"},
+		optional: []string{"subheading", "size", "style", "button_text", "button_url", "background_image"},
+		check: func(a map[string]string) string {
+			if msg := oneOf(a, "size", "small", "medium", "large"); msg != "" {
+				return msg
+			}
+			if msg := oneOf(a, "style", "dark", "light", "accent", "image"); msg != "" {
+				return msg
+			}
+			if a["style"] == "image" && a["background_image"] == "" {
+				return `style="image" needs background_image`
+			}
+			return pair(a, "button_text", "button_url")
+		},
+	},
+	"product": {
+		required: []string{"title"},
+		optional: []string{"image", "rating", "button_text", "button_url"},
+		body:     true,
+		check: func(a map[string]string) string {
+			if r, ok := a["rating"]; ok {
+				if n, err := strconv.Atoi(r); err != nil || n < 1 || n > 5 {
+					return fmt.Sprintf("rating must be 1 to 5, not %q", r)
+				}
+			}
+			return pair(a, "button_text", "button_url")
+		},
+	},
+}
+
+func oneOf(a map[string]string, key string, values ...string) string {
+	if v, ok := a[key]; ok && !slices.Contains(values, v) {
+		return fmt.Sprintf("%s must be one of %s, not %q", key, strings.Join(values, ", "), v)
+	}
+	return ""
+}
+
+func pair(a map[string]string, x, y string) string {
+	if (a[x] == "") != (a[y] == "") {
+		return fmt.Sprintf("%s and %s go together", x, y)
+	}
+	return ""
+}
+
+// shortcodeOpenRe matches an opening fence: :::name{attrs}.
+var shortcodeOpenRe = regexp.MustCompile(`^(:{3,})\s*([A-Za-z][\w-]*)\s*(\{.*\})?\s*$`)
+
+type shortcodeParser struct{}
+
+func (shortcodeParser) Trigger() []byte { return []byte{':'} }
+
+func (shortcodeParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 {
+		return nil, parser.NoChildren
+	}
+	m := shortcodeOpenRe.FindSubmatch(bytes.TrimRight(line[pos:], "\r\n"))
+	if m == nil {
+		return nil, parser.NoChildren
+	}
+	n := &shortcode{
+		Name:  string(m[2]),
+		Attrs: map[string]string{},
+		Line:  lineOf(reader.Source(), segment.Start),
+		fence: len(m[1]),
+	}
+	if len(m[3]) > 0 {
+		n.Attrs = parseAttrs(string(m[3][1 : len(m[3])-1]))
+	}
+	reader.Advance(segment.Len() - 1)
+	return n, parser.HasChildren
+}
+
+func (shortcodeParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	n := node.(*shortcode)
+	line, segment := reader.PeekLine()
+	if w, pos := util.IndentWidth(line, reader.LineOffset()); w < 4 {
+		fence := bytes.TrimRight(line[pos:], " \t\r\n")
+		if len(fence) == n.fence && bytes.Count(fence, []byte(":")) == n.fence {
+			reader.Advance(segment.Len() - 1)
+			n.closed = true
+			return parser.Close
+		}
+	}
+	return parser.Continue | parser.HasChildren
+}
+
+func (shortcodeParser) Close(ast.Node, text.Reader, parser.Context) {}
+
+func (shortcodeParser) CanInterruptParagraph() bool { return true }
+
+func (shortcodeParser) CanAcceptIndentedLine() bool { return false }
+
+// shortcodes checks every shortcode against its card, reporting what's
+// wrong, and unwraps a callout's only paragraph, as Ghost's callout text
+// has no <p>.
+type shortcodes struct{}
+
+func (shortcodes) Transform(doc *ast.Document, _ text.Reader, pc parser.Context) {
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		n, ok := node.(*shortcode)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		if msg := n.check(); msg != "" {
+			report(pc, &Error{Line: n.Line, Msg: msg})
+		}
+		if p, ok := n.FirstChild().(*ast.Paragraph); ok && n.Name == "callout" && n.ChildCount() == 1 {
+			for c := p.FirstChild(); c != nil; {
+				next := c.NextSibling()
+				n.AppendChild(n, c)
+				c = next
+			}
+			n.RemoveChild(n, p)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+func (n *shortcode) check() string {
+	spec, ok := shortcodeSpecs[n.Name]
+	if !ok {
+		names := make([]string, 0, len(shortcodeSpecs))
+		for name := range shortcodeSpecs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Sprintf("unknown card %q (want %s)", n.Name, strings.Join(names, ", "))
+	}
+	if !n.closed {
+		return fmt.Sprintf("%s is never closed (end it with a line of %s)", n.Name, strings.Repeat(":", n.fence))
+	}
+	known := append(slices.Clone(spec.required), spec.optional...)
+	keys := make([]string, 0, len(n.Attrs))
+	for k := range n.Attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !slices.Contains(known, k) {
+			return fmt.Sprintf("%s: unknown attribute %q (want %s)", n.Name, k, strings.Join(known, ", "))
+		}
+	}
+	for _, k := range spec.required {
+		if n.Attrs[k] == "" {
+			return fmt.Sprintf("%s: %s is required", n.Name, k)
+		}
+	}
+	if !spec.body && n.HasChildren() {
+		return fmt.Sprintf("%s takes no content, only attributes", n.Name)
+	}
+	if spec.check != nil {
+		if msg := spec.check(n.Attrs); msg != "" {
+			return n.Name + ": " + msg
+		}
+	}
+	return ""
+}
+
+// Ghost's toggle chevron and rating star.
+const (
+	toggleIcon = `<svg id="Regular" xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 24"><path class="cls-1" d="M23.25,7.311,12.53,18.03a.749.749,0,0,1-1.06,0L.75,7.311"></path></svg>`
+	ratingStar = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 24"><path d="M12.729,1.2l3.346,6.629,6.44.638a.805.805,0,0,1,.5,1.374l-5.3,5.253,1.965,7.138a.813.813,0,0,1-1.151.935L12,19.934,5.48,23.163a.813.813,0,0,1-1.151-.935L6.294,15.09.99,9.837a.805.805,0,0,1,.5-1.374l6.44-.638L11.271,1.2A.819.819,0,0,1,12.729,1.2Z"/></svg>`
+)
+
+func renderShortcode(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*shortcode)
+	a := n.Attrs
+	e := html.EscapeString
+
+	switch n.Name {
+	case "callout":
+		if !entering {
+			w.WriteString("</div></div>\n")
+			break
+		}
+		color := a["color"]
+		if color == "" {
+			color = "grey"
+		}
+		fmt.Fprintf(w, `<div class="kg-card kg-callout-card kg-callout-card-%s">`, color)
+		if a["emoji"] != "" {
+			fmt.Fprintf(w, `<div class="kg-callout-emoji">%s</div>`, e(a["emoji"]))
+		}
+		w.WriteString(`<div class="kg-callout-text">`)
+	case "toggle":
+		if !entering {
+			w.WriteString("</div></div>\n")
+			break
+		}
+		fmt.Fprintf(w, `<div class="kg-card kg-toggle-card" data-kg-toggle-state="close"><div class="kg-toggle-heading">`+
+			`<h4 class="kg-toggle-heading-text">%s</h4><button class="kg-toggle-card-icon" aria-label="Expand toggle to read content">%s</button></div>`+
+			`<div class="kg-toggle-content">`, e(a["heading"]), toggleIcon)
+	case "button":
+		if !entering {
+			break
+		}
+		align := a["align"]
+		if align == "" {
+			align = "left"
+		}
+		fmt.Fprintf(w, `<div class="kg-card kg-button-card kg-align-%s"><a href="%s" class="kg-btn kg-btn-accent">%s</a></div>`+"\n",
+			align, e(a["url"]), e(a["text"]))
+	case "bookmark":
+		if !entering {
+			break
+		}
+		class := "kg-card kg-bookmark-card"
+		if a["caption"] != "" {
+			class += " kg-card-hascaption"
+		}
+		fmt.Fprintf(w, `<figure class="%s"><a class="kg-bookmark-container" href="%s"><div class="kg-bookmark-content">`+
+			`<div class="kg-bookmark-title">%s</div><div class="kg-bookmark-description">%s</div><div class="kg-bookmark-metadata">`,
+			class, e(a["url"]), e(a["title"]), e(a["description"]))
+		if a["icon"] != "" {
+			fmt.Fprintf(w, `<img class="kg-bookmark-icon" src="%s" alt="">`, e(a["icon"]))
+		}
+		if a["author"] != "" {
+			fmt.Fprintf(w, `<span class="kg-bookmark-author">%s</span>`, e(a["author"]))
+		}
+		if a["publisher"] != "" {
+			fmt.Fprintf(w, `<span class="kg-bookmark-publisher">%s</span>`, e(a["publisher"]))
+		}
+		w.WriteString(`</div></div>`)
+		if a["thumbnail"] != "" {
+			fmt.Fprintf(w, `<div class="kg-bookmark-thumbnail"><img src="%s" alt=""></div>`, e(a["thumbnail"]))
+		}
+		w.WriteString(`</a>`)
+		if a["caption"] != "" {
+			fmt.Fprintf(w, `<figcaption>%s</figcaption>`, e(a["caption"]))
+		}
+		w.WriteString("</figure>\n")
+	case "header":
+		if !entering {
+			break
+		}
+		size, style := a["size"], a["style"]
+		if size == "" {
+			size = "small"
+		}
+		if style == "" {
+			style = "dark"
+		}
+		bg := ""
+		if style == "image" {
+			bg = fmt.Sprintf(` style="background-image: url(%s)" data-kg-background-image="%s"`, e(a["background_image"]), e(a["background_image"]))
+		}
+		fmt.Fprintf(w, `<div class="kg-card kg-header-card kg-width-full kg-size-%s kg-style-%s"%s>`+
+			`<h2 class="kg-header-card-header">%s</h2>`, size, style, bg, e(a["heading"]))
+		if a["subheading"] != "" {
+			fmt.Fprintf(w, `<h3 class="kg-header-card-subheader">%s</h3>`, e(a["subheading"]))
+		}
+		if a["button_url"] != "" {
+			fmt.Fprintf(w, `<a href="%s" class="kg-header-card-button">%s</a>`, e(a["button_url"]), e(a["button_text"]))
+		}
+		w.WriteString("</div>\n")
+	case "product":
+		if !entering {
+			w.WriteString(`</div>`)
+			if a["button_url"] != "" {
+				fmt.Fprintf(w, `<a href="%s" class="kg-product-card-button kg-product-card-btn-accent" target="_blank" rel="noopener noreferrer"><span>%s</span></a>`,
+					e(a["button_url"]), e(a["button_text"]))
+			}
+			w.WriteString("</div></div>\n")
+			break
+		}
+		w.WriteString(`<div class="kg-card kg-product-card"><div class="kg-product-card-container">`)
+		if a["image"] != "" {
+			fmt.Fprintf(w, `<img src="%s" class="kg-product-card-image" loading="lazy">`, e(a["image"]))
+		}
+		fmt.Fprintf(w, `<div class="kg-product-card-title-container"><h4 class="kg-product-card-title">%s</h4></div>`, e(a["title"]))
+		if r, err := strconv.Atoi(a["rating"]); err == nil {
+			w.WriteString(`<div class="kg-product-card-rating">`)
+			for i := 1; i <= 5; i++ {
+				active := ""
+				if i <= r {
+					active = "kg-product-card-rating-active "
+				}
+				fmt.Fprintf(w, `<span class="%skg-product-card-rating-star">%s</span>`, active, ratingStar)
+			}
+			w.WriteString(`</div>`)
+		}
+		w.WriteString(`<div class="kg-product-card-description">`)
+	}
+	if spec := shortcodeSpecs[n.Name]; !spec.body {
+		return ast.WalkSkipChildren, nil
+	}
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,192 @@
+// internal/render/shortcode_test.go
+
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShortcodes(t *testing.T) {
+	tests := []struct {
+		name, md, want string
+	}{
+		{
+			"callout text has no paragraph",
+			":::callout{emoji=\"💡\" color=\"blue\"}\nRemember to **save**.\n:::\n",
+			`<div class="kg-card kg-callout-card kg-callout-card-blue"><div class="kg-callout-emoji">💡</div><div class="kg-callout-text">Remember to <strong>save</strong>.</div></div>` + "\n",
+		},
+		{
+			"callout with paragraphs",
+			":::callout\nOne.\n\nTwo.\n:::\n",
+			`<div class="kg-card kg-callout-card kg-callout-card-grey"><div class="kg-callout-text"><p>One.</p>` + "\n<p>Two.</p>\n</div></div>\n",
+		},
+		{
+			"interrupts a paragraph",
+			"Intro\n:::callout\nx\n:::\n",
+			"<p>Intro</p>\n" + `<div class="kg-card kg-callout-card kg-callout-card-grey"><div class="kg-callout-text">x</div></div>` + "\n",
+		},
+		{
+			"button in a toggle",
+			"::::toggle{heading=\"Q & A\"}\nText.\n\n:::button{url=\"https://e.com\" text=\"Go\" align=\"center\"}\n:::\n::::\n",
+			`<div class="kg-card kg-toggle-card" data-kg-toggle-state="close"><div class="kg-toggle-heading"><h4 class="kg-toggle-heading-text">Q &amp; A</h4>` +
+				`<button class="kg-toggle-card-icon" aria-label="Expand toggle to read content">` + toggleIcon + `</button></div>` +
+				`<div class="kg-toggle-content"><p>Text.</p>` + "\n" +
+				`<div class="kg-card kg-button-card kg-align-center"><a href="https://e.com" class="kg-btn kg-btn-accent">Go</a></div>` + "\n</div></div>\n",
+		},
+		{
+			"bookmark",
+			":::bookmark{url=\"https://e.com\" title=\"E\" description=\"D\" caption=\"C\"}\n:::\n",
+			`<figure class="kg-card kg-bookmark-card kg-card-hascaption"><a class="kg-bookmark-container" href="https://e.com"><div class="kg-bookmark-content">` +
+				`<div class="kg-bookmark-title">E</div><div class="kg-bookmark-description">D</div><div class="kg-bookmark-metadata"></div></div></a><figcaption>C</figcaption></figure>` + "\n",
+		},
+		{
+			"header",
+			":::header{heading=\"Hi\" subheading=\"There\" size=\"large\" style=\"light\" button_text=\"Join\" button_url=\"https://e.com\"}\n:::\n",
+			`<div class="kg-card kg-header-card kg-width-full kg-size-large kg-style-light"><h2 class="kg-header-card-header">Hi</h2>` +
+				`<h3 class="kg-header-card-subheader">There</h3><a href="https://e.com" class="kg-header-card-button">Join</a></div>` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HTML([]byte(tt.md), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("HTML(%q)\n got %q\nwant %q", tt.md, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProductRating(t *testing.T) {
+	md := ":::product{title=\"Pen\" rating=\"4\" image=\"https://e.com/p.png\" button_text=\"Buy\" button_url=\"https://e.com\"}\nWrites well.\n:::\n"
+	got, err := HTML([]byte(md), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<img src="https://e.com/p.png" class="kg-product-card-image" loading="lazy">`,
+		`<h4 class="kg-product-card-title">Pen</h4>`,
+		`<div class="kg-product-card-description"><p>Writes well.</p>`,
+		`<a href="https://e.com" class="kg-product-card-button kg-product-card-btn-accent" target="_blank" rel="noopener noreferrer"><span>Buy</span></a>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("product card lacks %s:\n%s", want, got)
+		}
+	}
+	if on, all := strings.Count(got, "kg-product-card-rating-active"), strings.Count(got, ratingStar); on != 4 || all != 5 {
+		t.Errorf("%d of %d stars lit, want 4 of 5", on, all)
+	}
+}
+
+func TestShortcodeLexical(t *testing.T) {
+	tests := []struct {
+		name, md, want string
+	}{
+		{
+			"callout defaults",
+			":::callout\nA *tip*.\n:::\n",
+			`{"backgroundColor":"grey","calloutEmoji":"","calloutText":"A <em>tip</em>.","type":"callout","version":1}`,
+		},
+		{
+			"toggle",
+			":::toggle{heading=\"Q & A\"}\nText.\n:::\n",
+			`{"content":"<p>Text.</p>","heading":"Q &amp; A","type":"toggle","version":1}`,
+		},
+		{
+			"button",
+			":::button{url=\"https://e.com\" text=\"Go\"}\n:::\n",
+			`{"alignment":"left","buttonText":"Go","buttonUrl":"https://e.com","type":"button","version":1}`,
+		},
+		{
+			"bookmark",
+			":::bookmark{url=\"https://e.com\" title=\"E\"}\n:::\n",
+			`{"caption":"","metadata":{"author":"","description":"","icon":"","publisher":"","thumbnail":"","title":"E"},"type":"bookmark","url":"https://e.com","version":1}`,
+		},
+		{
+			"header",
+			":::header{heading=\"Hi\"}\n:::\n",
+			`{"backgroundImageSrc":"","buttonEnabled":false,"buttonText":"","buttonUrl":"","header":"Hi","size":"small","style":"dark","subheader":"","type":"header","version":1}`,
+		},
+		{
+			"product without a rating",
+			":::product{title=\"Pen\"}\nWrites.\n:::\n",
+			`{"productButton":"","productButtonEnabled":false,"productDescription":"<p>Writes.</p>","productImageHeight":null,"productImageSrc":"","productImageWidth":null,` +
+				`"productRatingEnabled":false,"productStarRating":5,"productTitle":"Pen","productUrl":"","type":"product","version":1}`,
+		},
+	}
+	lexical, err := New("lexical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := lexical.Render([]byte(tt.md), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc struct {
+				Root struct {
+					Children []any `json:"children"`
+				} `json:"root"`
+			}
+			var want any
+			if err := json.Unmarshal([]byte(out.Lexical), &doc); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("bad want: %v", err)
+			}
+			if len(doc.Root.Children) != 1 || !reflect.DeepEqual(doc.Root.Children[0], want) {
+				raw, _ := json.Marshal(doc.Root.Children)
+				t.Errorf("Lexical of %q\n got %s\nwant [%s]", tt.md, raw, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortcodeErrors(t *testing.T) {
+	tests := []struct {
+		name, md string
+		line     int
+		msg      string
+	}{
+		{"unknown card", "# Post\n\n:::callot\nx\n:::\n", 3, `unknown card "callot" (want bookmark, button, callout, header, product, toggle)`},
+		{"required attribute", ":::toggle\nno heading\n:::\n", 1, "toggle: heading is required"},
+		{"empty required attribute", ":::toggle{heading=\"\"}\nx\n:::\n", 1, "toggle: heading is required"},
+		{"unknown attribute", "Text.\n\n:::callout{size=\"x\"}\n:::\n", 3, `callout: unknown attribute "size" (want emoji, color)`},
+		{"value not allowed", ":::button{url=\"x\" text=\"y\" align=\"right\"}\n:::\n", 1, `button: align must be one of left, center, not "right"`},
+		{"content not taken", ":::button{url=\"x\" text=\"y\"}\nbody\n:::\n", 1, "button takes no content, only attributes"},
+		{"attributes go together", ":::header{heading=\"h\" button_text=\"b\"}\n:::\n", 1, "header: button_text and button_url go together"},
+		{"image header needs an image", ":::header{heading=\"h\" style=\"image\"}\n:::\n", 1, `header: style="image" needs background_image`},
+		{"rating out of range", ":::product{title=\"p\" rating=\"9\"}\n:::\n", 1, `product: rating must be 1 to 5, not "9"`},
+		{"never closed", "One.\n\nTwo.\n\n:::callout\nnever closed\n", 5, "callout is never closed (end it with a line of :::)"},
+		{"inner fence too short", "::::toggle{heading=\"h\"}\n:::callout\nx\n::::\n", 2, "callout is never closed (end it with a line of :::)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := HTML([]byte(tt.md), Options{})
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("err = %v, want an *Error", err)
+			}
+			if e.Line != tt.line || e.Msg != tt.msg {
+				t.Errorf("err = line %d: %s\nwant line %d: %s", e.Line, e.Msg, tt.line, tt.msg)
+			}
+		})
+	}
+}
+
+func TestShortcodeErrorsAreJoined(t *testing.T) {
+	md := ":::toggle\nx\n:::\n\n:::button{url=\"x\" text=\"y\" align=\"right\"}\n:::\n"
+	_, err := HTML([]byte(md), Options{})
+	want := "line 1: toggle: heading is required\nline 5: button: align must be one of left, center, not \"right\""
+	if err == nil || err.Error() != want {
+		t.Errorf("err = %v\nwant %s", err, want)
+	}
+}
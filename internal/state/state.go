@@ -0,0 +1,143 @@
+// internal/state/state.go
+
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Entry is what ghostpost remembers about one published Markdown file.
+type Entry struct {
+	Slug            string            `json:"slug,omitempty"`
+	PostID          string            `json:"post_id"`
+	Hash            string            `json:"hash,omitempty"`
+	RemoteUpdatedAt string            `json:"remote_updated_at,omitempty"`
+	RemoteHash      string            `json:"remote_hash,omitempty"`
+	Images          map[string]string `json:"images,omitempty"` // local ref → Ghost URL
+}
+
+// File is a repository-level state file, keyed by Markdown path relative
+// to the repository root. It is safe for concurrent use.
+type File struct {
+	path string
+	root string
+
+	mu    sync.Mutex
+	Posts map[string]Entry `json:"posts"`
+}
+
+// Load reads the state file at path. A missing file is an empty state.
+// Paths are keyed relative to the directory holding .ghostpost/, or to the
+// file's own directory if it lives elsewhere.
+func Load(path string) (*File, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{path: abs, root: filepath.Dir(abs), Posts: map[string]Entry{}}
+	if filepath.Base(f.root) == ".ghostpost" {
+		f.root = filepath.Dir(f.root)
+	}
+
+	raw, err := os.ReadFile(abs)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if f.Posts == nil {
+		f.Posts = map[string]Entry{}
+	}
+	return f, nil
+}
+
+func (f *File) key(file string) (string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(f.root, abs)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Get finds the entry for file. If the file was renamed, the entry is
+// found by slug instead.
+func (f *File) Get(file, slug string) (Entry, bool) {
+	key, err := f.key(file)
+	if err != nil {
+		return Entry{}, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, ok := f.Posts[key]; ok {
+		return e, true
+	}
+	if slug == "" {
+		return Entry{}, false
+	}
+	for k, e := range f.Posts {
+		if e.Slug == slug {
+			if _, err := os.Stat(filepath.Join(f.root, k)); err != nil {
+				return e, true // only steal entries whose file is gone
+			}
+		}
+	}
+	return Entry{}, false
+}
+
+// Put records e for file, drops any entry a rename left behind, and saves.
+func (f *File) Put(file string, e Entry) error {
+	key, err := f.key(file)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for k, old := range f.Posts {
+		if k != key && old.PostID == e.PostID {
+			delete(f.Posts, k)
+		}
+	}
+	f.Posts[key] = e
+	return f.save()
+}
+
+// Delete forgets file and saves.
+func (f *File) Delete(file string) error {
+	key, err := f.key(file)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.Posts, key)
+	return f.save()
+}
+
+// save writes the state atomically, so a crash never leaves half a file.
+func (f *File) save() error {
+	buf, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, append(buf, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
@@ -0,0 +1,159 @@
+// internal/state/state_test.go
+
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// repo makes a repository with the given files and returns the path of
+// its state file.
+func repo(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, f := range files {
+		p := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(root, ".ghostpost", "state.json")
+}
+
+func TestPutGet(t *testing.T) {
+	path := repo(t, "posts/a.md")
+	root := filepath.Dir(filepath.Dir(path))
+	f, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := Entry{Slug: "a", PostID: "p1", Hash: "h1", Images: map[string]string{"pic.png": "https://x/pic.png"}}
+	if err := f.Put(filepath.Join(root, "posts/a.md"), e); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.Posts["posts/a.md"]; !ok {
+		t.Errorf("keys = %v, want posts/a.md relative to the repository", f.Posts)
+	}
+	got, ok := f.Get(filepath.Join(root, "posts/a.md"), "")
+	if !ok || !reflect.DeepEqual(got, e) {
+		t.Errorf("Get = %+v, %v, want %+v", got, ok, e)
+	}
+	if _, ok := f.Get(filepath.Join(root, "posts/b.md"), ""); ok {
+		t.Error("Get found an entry for a file never put")
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	f, err := Load(repo(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Posts) != 0 {
+		t.Errorf("Posts = %v, want none", f.Posts)
+	}
+}
+
+func TestRename(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string // on disk
+		slug     string
+		wantFind bool
+	}{
+		{"renamed", []string{"posts/new.md"}, "a", true},
+		{"other slug", []string{"posts/new.md"}, "b", false},
+		{"no slug", []string{"posts/new.md"}, "", false},
+		{"copy, old file still there", []string{"posts/old.md", "posts/new.md"}, "a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := repo(t, tt.files...)
+			root := filepath.Dir(filepath.Dir(path))
+			f, _ := Load(path)
+			old := Entry{Slug: "a", PostID: "p1", Hash: "h1"}
+			f.Posts["posts/old.md"] = old
+
+			got, ok := f.Get(filepath.Join(root, "posts/new.md"), tt.slug)
+			if ok != tt.wantFind || (ok && !reflect.DeepEqual(got, old)) {
+				t.Errorf("Get by slug %q = %+v, %v, want found: %v", tt.slug, got, ok, tt.wantFind)
+			}
+		})
+	}
+}
+
+func TestPutDropsRenamedEntry(t *testing.T) {
+	path := repo(t, "posts/new.md")
+	root := filepath.Dir(filepath.Dir(path))
+	f, _ := Load(path)
+	f.Posts["posts/old.md"] = Entry{Slug: "a", PostID: "p1"}
+	f.Posts["posts/other.md"] = Entry{Slug: "b", PostID: "p2"}
+
+	if err := f.Put(filepath.Join(root, "posts/new.md"), Entry{Slug: "a", PostID: "p1", Hash: "h2"}); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for k := range f.Posts {
+		keys = append(keys, k)
+	}
+	if len(f.Posts) != 2 || f.Posts["posts/new.md"].Hash != "h2" || f.Posts["posts/other.md"].PostID != "p2" {
+		t.Errorf("entries = %v, want posts/new.md and posts/other.md", keys)
+	}
+}
+
+func TestSaveIsAtomic(t *testing.T) {
+	path := repo(t, "posts/a.md")
+	root := filepath.Dir(filepath.Dir(path))
+	f, _ := Load(path)
+	if err := f.Put(filepath.Join(root, "posts/a.md"), Entry{PostID: "p1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+	before, _ := os.ReadFile(path)
+
+	// A save that fails before the rename leaves the state as it was.
+	if err := os.Mkdir(path+".tmp", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Put(filepath.Join(root, "posts/a.md"), Entry{PostID: "p2"}); err == nil {
+		t.Fatal("Put succeeded without a place to write")
+	}
+	after, _ := os.ReadFile(path)
+	if string(after) != string(before) {
+		t.Errorf("state file changed by a failed save:\n%s", after)
+	}
+	var check File
+	if err := json.Unmarshal(after, &check); err != nil {
+		t.Errorf("state file is not valid JSON: %v", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	path := repo(t, "posts/a.md")
+	root := filepath.Dir(filepath.Dir(path))
+	f, _ := Load(path)
+	file := filepath.Join(root, "posts/a.md")
+	if err := f.Put(file, Entry{PostID: "p1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Delete(file); err != nil {
+		t.Fatal(err)
+	}
+	f, _ = Load(path)
+	if len(f.Posts) != 0 {
+		t.Errorf("Posts = %v after Delete, want none", f.Posts)
+	}
+}
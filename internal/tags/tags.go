@@ -0,0 +1,150 @@
+// internal/tags/tags.go
+
+package tags
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/api"
+	"gopkg.in/yaml.v3"
+)
+
+// Tag is one entry in tags.yaml.
+type Tag struct {
+	Name            string `yaml:"name"`
+	Slug            string `yaml:"slug,omitempty"`
+	Description     string `yaml:"description,omitempty"`
+	AccentColor     string `yaml:"accent_color,omitempty"`
+	FeatureImage    string `yaml:"feature_image,omitempty"`
+	MetaTitle       string `yaml:"meta_title,omitempty"`
+	MetaDescription string `yaml:"meta_description,omitempty"`
+	Visibility      string `yaml:"visibility,omitempty"` // public | internal; #tags are internal
+}
+
+// Load reads a tags file. A missing file has no tags.
+func Load(path string) ([]Tag, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []Tag
+	if err := yaml.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	seen := map[string]bool{}
+	for i, t := range out {
+		if t.Name == "" {
+			return nil, fmt.Errorf("%s: tag #%d has no name", path, i+1)
+		}
+		if seen[key(t.Name)] {
+			return nil, fmt.Errorf("%s: tag %q is listed twice", path, t.Name)
+		}
+		seen[key(t.Name)] = true
+	}
+	return out, nil
+}
+
+// Save writes tags to path.
+func Save(path string, tags []Tag) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(tags); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// FromAPI converts a Ghost tag to a tags.yaml entry.
+func FromAPI(t api.Tag) Tag {
+	out := Tag{
+		Name:            t.Name,
+		Slug:            t.Slug,
+		Description:     t.Description,
+		AccentColor:     t.AccentColor,
+		FeatureImage:    t.FeatureImage,
+		MetaTitle:       t.MetaTitle,
+		MetaDescription: t.MetaDescription,
+		Visibility:      t.Visibility,
+	}
+	if out.Visibility == defaultVisibility(t.Name) {
+		out.Visibility = ""
+	}
+	return out
+}
+
+// API converts a tags.yaml entry to a Ghost tag.
+func (t Tag) API() api.Tag {
+	return api.Tag{
+		Name:            t.Name,
+		Slug:            t.Slug,
+		Description:     t.Description,
+		FeatureImage:    t.FeatureImage,
+		Visibility:      t.visibility(),
+		MetaTitle:       t.MetaTitle,
+		MetaDescription: t.MetaDescription,
+		AccentColor:     t.AccentColor,
+	}
+}
+
+// Equal reports whether Ghost's tag already matches t.
+func (t Tag) Equal(r api.Tag) bool {
+	return t.Name == r.Name &&
+		(t.Slug == "" || t.Slug == r.Slug) &&
+		t.Description == r.Description &&
+		t.FeatureImage == r.FeatureImage &&
+		t.visibility() == r.Visibility &&
+		t.MetaTitle == r.MetaTitle &&
+		t.MetaDescription == r.MetaDescription &&
+		t.AccentColor == r.AccentColor
+}
+
+// Matches reports whether r is the Ghost tag for t: same slug if t has
+// one, else the same name. Names compare case-insensitively, as in Ghost.
+func (t Tag) Matches(r api.Tag) bool {
+	if t.Slug != "" {
+		return t.Slug == r.Slug
+	}
+	return key(t.Name) == key(r.Name)
+}
+
+func (t Tag) visibility() string {
+	if t.Visibility == "" {
+		return defaultVisibility(t.Name)
+	}
+	return t.Visibility
+}
+
+// defaultVisibility is what Ghost picks: tags starting with # are internal.
+func defaultVisibility(name string) string {
+	if strings.HasPrefix(name, "#") {
+		return "internal"
+	}
+	return "public"
+}
+
+func key(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Used reports whether a post tagged with names uses r.
+func Used(r api.Tag, names map[string]bool) bool {
+	return names[key(r.Name)] || names[key(r.Slug)]
+}
+
+// Names builds the set Used checks against from post tag names.
+func Names(names []string, into map[string]bool) {
+	for _, n := range names {
+		into[key(n)] = true
+	}
+}
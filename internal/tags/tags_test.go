@@ -0,0 +1,130 @@
+// internal/tags/tags_test.go
+
+package tags
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rodchristiansen/ghost-gitops-publishing/internal/api"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name, yaml, wantErr string
+		want                []Tag
+	}{
+		{"tags", "- name: Go\n  slug: golang\n- name: '#internal'\n", "", []Tag{{Name: "Go", Slug: "golang"}, {Name: "#internal"}}},
+		{"no name", "- slug: go\n", "tag #1 has no name", nil},
+		{"listed twice", "- name: Go\n- name: ' go '\n", `tag " go " is listed twice`, nil},
+		{"not a list", "name: Go\n", "cannot unmarshal", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tags.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	got, err := Load(filepath.Join(t.TempDir(), "tags.yaml"))
+	if err != nil || got != nil {
+		t.Errorf("Load = %v, %v, want no tags", got, err)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tags.yaml")
+	want := []Tag{{Name: "Go", Slug: "go", Description: "The language", AccentColor: "#00ADD8"}, {Name: "#news", Visibility: "public"}}
+	if err := Save(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(path)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Load after Save = %+v, %v, want %+v", got, err, want)
+	}
+}
+
+func TestVisibility(t *testing.T) {
+	tests := []struct {
+		name, visibility string
+		wantAPI          string // sent to Ghost
+		wantFile         string // kept in tags.yaml by FromAPI
+	}{
+		{"Go", "", "public", ""},
+		{"#internal", "", "internal", ""},
+		{"Go", "internal", "internal", "internal"},
+		{"#shown", "public", "public", "public"},
+	}
+	for _, tt := range tests {
+		l := Tag{Name: tt.name, Visibility: tt.visibility}
+		r := l.API()
+		if r.Visibility != tt.wantAPI {
+			t.Errorf("%s, %q: API visibility = %q, want %q", tt.name, tt.visibility, r.Visibility, tt.wantAPI)
+		}
+		if got := FromAPI(r).Visibility; got != tt.wantFile {
+			t.Errorf("%s, %q: FromAPI visibility = %q, want %q", tt.name, tt.visibility, got, tt.wantFile)
+		}
+		if !l.Equal(r) {
+			t.Errorf("%s, %q: Equal(API()) = false", tt.name, tt.visibility)
+		}
+	}
+}
+
+func TestMatchesAndEqual(t *testing.T) {
+	r := api.Tag{ID: "t1", Name: "Go", Slug: "golang", Description: "d", Visibility: "public"}
+	tests := []struct {
+		name         string
+		local        Tag
+		match, equal bool
+	}{
+		{"same", Tag{Name: "Go", Slug: "golang", Description: "d"}, true, true},
+		{"name, any case", Tag{Name: " go ", Description: "d"}, true, false},
+		{"no slug leaves it to Ghost", Tag{Name: "Go", Description: "d"}, true, true},
+		{"slug wins over name", Tag{Name: "Go", Slug: "go"}, false, false},
+		{"description differs", Tag{Name: "Go", Slug: "golang"}, true, false},
+		{"visibility differs", Tag{Name: "Go", Slug: "golang", Description: "d", Visibility: "internal"}, true, false},
+	}
+	for _, tt := range tests {
+		if got := tt.local.Matches(r); got != tt.match {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.match)
+		}
+		if got := tt.local.Equal(r); got != tt.equal {
+			t.Errorf("%s: Equal = %v, want %v", tt.name, got, tt.equal)
+		}
+	}
+}
+
+func TestUsed(t *testing.T) {
+	names := map[string]bool{}
+	Names([]string{"Go", " Web Dev "}, names)
+	tests := []struct {
+		tag  api.Tag
+		want bool
+	}{
+		{api.Tag{Name: "go", Slug: "golang"}, true},
+		{api.Tag{Name: "Web development", Slug: "web dev"}, true},
+		{api.Tag{Name: "Rust", Slug: "rust"}, false},
+	}
+	for _, tt := range tests {
+		if got := Used(tt.tag, names); got != tt.want {
+			t.Errorf("Used(%s) = %v, want %v", tt.tag.Name, got, tt.want)
+		}
+	}
+}